@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+)
+
+// errAmbiguousCard é devolvido quando a busca fuzzy corresponde a várias cartas (ex: "forest")
+var errAmbiguousCard = errors.New("nome ambíguo, mais de uma carta corresponde")
+
+// Tamanho mínimo do texto para pedir sugestões; o Scryfall não responde com menos de 2 letras
+const minAutocompleteLen = 2
+
+// Quantas sugestões aparecem sob o campo
+const maxSuggestions = 8
+
+// autocomplete retorna até 20 nomes de cartas que começam com ou contêm o texto
+func (d *Downloader) autocomplete(ctx context.Context, query string) ([]string, error) {
+	if d.useCatalog {
+		c, err := d.openCatalog()
+		if err != nil {
+			return nil, err
+		}
+		return c.autocomplete(query), nil
+	}
+
+	resp, err := d.getAPI(ctx, d.apiURL("/cards/autocomplete?q="+url.QueryEscape(query)))
+	if err != nil {
+		return nil, fmt.Errorf("erro ao buscar sugestões: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("erro ao buscar sugestões: HTTP %d", resp.StatusCode)
+	}
+
+	var catalog struct {
+		Data []string `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&catalog); err != nil {
+		return nil, fmt.Errorf("erro ao decodificar sugestões: %w", err)
+	}
+	return catalog.Data, nil
+}
+
+// fetchCardExact busca a carta com exatamente esse nome, como os escolhidos numa sugestão
+func (d *Downloader) fetchCardExact(ctx context.Context, cardName string) (*Card, error) {
+	return d.fetchCardURL(ctx, d.apiURL("/cards/named?exact="+url.QueryEscape(cardName)))
+}
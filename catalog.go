@@ -0,0 +1,667 @@
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// O catálogo offline guarda as cartas de um arquivo bulk data do Scryfall (default_cards ou
+// all_cards, https://scryfall.com/docs/api/bulk-data) numa pasta local:
+//
+//	catalog.json      sets, índice de nomes e impressões, origem e data da importação
+//	cards/<set>.json  cartas de cada set, carregadas sob demanda
+//
+// Com use_catalog ligado, a lista de sets, as cartas de cada set e a busca de carta por nome ou
+// número são respondidas pelo catálogo; só as imagens (e buscas livres) usam a rede.
+
+const catalogIndexFile = "catalog.json"
+
+type catalogIndex struct {
+	Source     string    `json:"source"`
+	ImportedAt time.Time `json:"imported_at"`
+	Cards      int       `json:"cards"`
+	Sets       []Set     `json:"sets"`
+
+	Names  map[string]string         `json:"names"`  // nome da carta e de cada face, em minúsculas → oracle_id
+	Oracle map[string]*catalogOracle `json:"oracle"` // oracle_id → carta
+}
+
+// catalogOracle é uma carta independente da impressão
+type catalogOracle struct {
+	Name   string   `json:"name"`
+	Prints []string `json:"prints"` // set/número de cada impressão, em qualquer idioma
+}
+
+type catalog struct {
+	dir   string
+	index *catalogIndex
+
+	mu    sync.Mutex
+	cards map[string][]Card // cartas por set, já lidas do disco
+}
+
+// bulkCard é uma carta do arquivo bulk data, que traz também os dados do set
+type bulkCard struct {
+	Card
+	SetName      string `json:"set_name"`
+	SetType      string `json:"set_type"`
+	SetSearchURI string `json:"set_search_uri"`
+	ReleasedAt   string `json:"released_at"`
+	Digital      bool   `json:"digital"`
+}
+
+func openCatalog(dir string) (*catalog, error) {
+	data, err := os.ReadFile(filepath.Join(dir, catalogIndexFile))
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("catálogo não encontrado em %s (importe com: catalog import <arquivo bulk>)", dir)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("erro ao ler catálogo: %w", err)
+	}
+	var index catalogIndex
+	if err := json.Unmarshal(data, &index); err != nil {
+		return nil, fmt.Errorf("erro ao decodificar catálogo: %w", err)
+	}
+	return &catalog{dir: dir, index: &index, cards: make(map[string][]Card)}, nil
+}
+
+// importCatalog lê um arquivo bulk data (JSON ou .json.gz) e grava o catálogo em dir,
+// substituindo o anterior só no final. progress recebe o total de cartas lidas até o momento.
+func importCatalog(ctx context.Context, path, dir string, progress func(cards int)) (*catalogIndex, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao abrir %s: %w", path, err)
+	}
+	defer file.Close()
+
+	var reader io.Reader = file
+	if strings.HasSuffix(strings.ToLower(path), ".gz") {
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			return nil, fmt.Errorf("erro ao descompactar %s: %w", path, err)
+		}
+		defer gz.Close()
+		reader = gz
+	}
+
+	// O arquivo é uma lista enorme (all_cards passa de 2 GB): decodifica uma carta por vez e
+	// guarda as cartas em disco, mantendo na memória só os índices de sets e nomes
+	decoder := json.NewDecoder(reader)
+	if token, err := decoder.Token(); err != nil || token != json.Delim('[') {
+		return nil, fmt.Errorf("%s não é um arquivo bulk data do Scryfall (esperada uma lista de cartas)", path)
+	}
+
+	if err := checkCatalogDir(dir); err != nil {
+		return nil, err
+	}
+
+	// Grava numa pasta temporária e troca no final, para não deixar um catálogo pela metade
+	tmpDir := dir + ".tmp"
+	os.RemoveAll(tmpDir)
+	if err := os.MkdirAll(filepath.Join(tmpDir, "cards"), 0755); err != nil {
+		return nil, fmt.Errorf("erro ao criar %s: %w", tmpDir, err)
+	}
+	defer os.RemoveAll(tmpDir) // Só sobra se a importação falhar no meio
+	spool := &catalogSpool{dir: filepath.Join(tmpDir, "spool"), pending: make(map[string][]Card)}
+	if err := os.MkdirAll(spool.dir, 0755); err != nil {
+		return nil, fmt.Errorf("erro ao criar %s: %w", spool.dir, err)
+	}
+
+	index := &catalogIndex{Source: filepath.Base(path), ImportedAt: time.Now(), Names: make(map[string]string), Oracle: make(map[string]*catalogOracle)}
+	sets := make(map[string]*Set)
+	for decoder.More() {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		var card bulkCard
+		if err := decoder.Decode(&card); err != nil {
+			return nil, fmt.Errorf("erro ao decodificar carta %d: %w", index.Cards+1, err)
+		}
+		if card.Set == "" || card.CollectorNumber == "" {
+			continue
+		}
+		index.Cards++
+		if progress != nil && index.Cards%10000 == 0 {
+			progress(index.Cards)
+		}
+		if err := spool.add(card.Set, card.Card); err != nil {
+			return nil, err
+		}
+
+		set, ok := sets[card.Set]
+		if !ok {
+			set = &Set{Code: card.Set, Name: card.SetName, SetType: card.SetType, SearchURI: card.SetSearchURI, ReleasedAt: card.ReleasedAt, Digital: card.Digital}
+			if set.SearchURI == "" {
+				set.SearchURI = defaultAPIBase + "/cards/search?q=" + url.QueryEscape("e:"+card.Set) + "&unique=prints"
+			}
+			sets[card.Set] = set
+		}
+		if card.ReleasedAt != "" && (set.ReleasedAt == "" || card.ReleasedAt < set.ReleasedAt) {
+			set.ReleasedAt = card.ReleasedAt
+		}
+		if card.Lang == "en" || card.Lang == "" {
+			set.CardCount++
+		}
+
+		// Cartas reversíveis não têm oracle_id próprio; o id da impressão serve de chave
+		oracleID := card.OracleID
+		if oracleID == "" {
+			oracleID = card.ID
+		}
+		oracle, ok := index.Oracle[oracleID]
+		if !ok {
+			oracle = &catalogOracle{Name: card.Name}
+			index.Oracle[oracleID] = oracle
+			index.Names[strings.ToLower(card.Name)] = oracleID
+			for _, face := range card.CardFaces {
+				if _, taken := index.Names[strings.ToLower(face.Name)]; !taken {
+					index.Names[strings.ToLower(face.Name)] = oracleID
+				}
+			}
+		}
+		if key := card.Set + "/" + card.CollectorNumber; !containsString(oracle.Prints, key) {
+			oracle.Prints = append(oracle.Prints, key)
+		}
+	}
+	if index.Cards == 0 {
+		return nil, fmt.Errorf("nenhuma carta encontrada em %s", path)
+	}
+	if err := spool.flush(); err != nil {
+		return nil, err
+	}
+
+	for code, set := range sets {
+		// O bulk data não traz parent_set_code; tokens, art series e promos de um set usam o
+		// código dele com uma letra na frente (tdom, adom, pdom)
+		if set.SetType == "token" || set.SetType == "memorabilia" || set.SetType == "promo" {
+			if _, ok := sets[code[1:]]; len(code) > 3 && ok {
+				set.ParentSetCode = code[1:]
+			}
+		}
+		index.Sets = append(index.Sets, *set)
+
+		// Um set por vez na memória: lê o que foi acumulado no disco, ordena e grava
+		cards, err := spool.read(code)
+		if err != nil {
+			return nil, err
+		}
+		sort.SliceStable(cards, func(i, j int) bool { return collectorLess(cards[i].CollectorNumber, cards[j].CollectorNumber) })
+		if err := writeJSONFile(filepath.Join(tmpDir, "cards", sanitizeFileName(code)+".json"), cards); err != nil {
+			return nil, err
+		}
+	}
+	sort.Slice(index.Sets, func(i, j int) bool {
+		if index.Sets[i].ReleasedAt != index.Sets[j].ReleasedAt {
+			return index.Sets[i].ReleasedAt > index.Sets[j].ReleasedAt
+		}
+		return index.Sets[i].Code < index.Sets[j].Code
+	})
+
+	if err := os.RemoveAll(spool.dir); err != nil {
+		return nil, err
+	}
+	if err := writeJSONFile(filepath.Join(tmpDir, catalogIndexFile), index); err != nil {
+		return nil, err
+	}
+
+	// O catálogo anterior só é apagado depois que o novo está no lugar
+	oldDir := dir + ".old"
+	os.RemoveAll(oldDir)
+	if err := checkCatalogDir(dir); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(dir, oldDir); err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("erro ao mover o catálogo anterior: %w", err)
+	}
+	if err := os.Rename(tmpDir, dir); err != nil {
+		os.Rename(oldDir, dir)
+		return nil, fmt.Errorf("erro ao gravar catálogo em %s: %w", dir, err)
+	}
+	if err := os.RemoveAll(oldDir); err != nil {
+		return nil, fmt.Errorf("erro ao remover o catálogo anterior: %w", err)
+	}
+	return index, nil
+}
+
+// checkCatalogDir recusa importar para uma pasta que tem outros arquivos: a importação substitui
+// a pasta inteira, então ela precisa estar vazia, não existir ou já conter um catálogo
+func checkCatalogDir(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("erro ao ler %s: %w", dir, err)
+	}
+	if len(entries) == 0 {
+		return nil
+	}
+	if _, err := os.Stat(filepath.Join(dir, catalogIndexFile)); err == nil {
+		return nil
+	}
+	return fmt.Errorf("%s não está vazia e não contém um catálogo; escolha outra pasta com -catalog-dir", dir)
+}
+
+// Cartas acumuladas na memória antes de irem para os arquivos temporários da importação
+const catalogSpoolCards = 5000
+
+// catalogSpool separa as cartas por set durante a importação. O bulk data não vem agrupado por
+// set, então cada carta vai para um arquivo temporário do set (JSON, uma carta por linha); os
+// arquivos são abertos só na hora de descarregar o buffer, para não esgotar os descritores.
+type catalogSpool struct {
+	dir     string
+	pending map[string][]Card
+	count   int
+}
+
+func (s *catalogSpool) path(code string) string {
+	return filepath.Join(s.dir, sanitizeFileName(code)+".jsonl")
+}
+
+func (s *catalogSpool) add(code string, card Card) error {
+	s.pending[code] = append(s.pending[code], card)
+	if s.count++; s.count >= catalogSpoolCards {
+		return s.flush()
+	}
+	return nil
+}
+
+// flush acrescenta as cartas do buffer ao arquivo de cada set
+func (s *catalogSpool) flush() error {
+	for code, cards := range s.pending {
+		file, err := os.OpenFile(s.path(code), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			return fmt.Errorf("erro ao gravar cartas de %s: %w", code, err)
+		}
+		writer := bufio.NewWriter(file)
+		encoder := json.NewEncoder(writer)
+		for i := range cards {
+			if err = encoder.Encode(&cards[i]); err != nil {
+				break
+			}
+		}
+		if err == nil {
+			err = writer.Flush()
+		}
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return fmt.Errorf("erro ao gravar cartas de %s: %w", code, err)
+		}
+	}
+	s.pending = make(map[string][]Card)
+	s.count = 0
+	return nil
+}
+
+// read devolve todas as cartas de um set gravadas por flush
+func (s *catalogSpool) read(code string) ([]Card, error) {
+	file, err := os.Open(s.path(code))
+	if err != nil {
+		return nil, fmt.Errorf("erro ao ler cartas de %s: %w", code, err)
+	}
+	defer file.Close()
+
+	var cards []Card
+	decoder := json.NewDecoder(bufio.NewReader(file))
+	for decoder.More() {
+		var card Card
+		if err := decoder.Decode(&card); err != nil {
+			return nil, fmt.Errorf("erro ao ler cartas de %s: %w", code, err)
+		}
+		cards = append(cards, card)
+	}
+	return cards, nil
+}
+
+func writeJSONFile(path string, value interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("erro ao gravar %s: %w", path, err)
+	}
+	return nil
+}
+
+// collectorLess ordena números de colecionador pela parte numérica: 2 < 10 < 10a < ★1
+func collectorLess(a, b string) bool {
+	na, restA := splitCollectorNumber(a)
+	nb, restB := splitCollectorNumber(b)
+	if na != nb {
+		return na < nb
+	}
+	return restA < restB
+}
+
+func splitCollectorNumber(number string) (int, string) {
+	digits := 0
+	for digits < len(number) && number[digits] >= '0' && number[digits] <= '9' {
+		digits++
+	}
+	if digits == 0 {
+		return 1 << 30, number // Sem número vai para o fim
+	}
+	n, _ := strconv.Atoi(number[:digits])
+	return n, number[digits:]
+}
+
+func (c *catalog) sets() []Set {
+	return append([]Set(nil), c.index.Sets...)
+}
+
+func (c *catalog) setCards(code string) ([]Card, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	code = strings.ToLower(code)
+	if cards, ok := c.cards[code]; ok {
+		return cards, nil
+	}
+	data, err := os.ReadFile(filepath.Join(c.dir, "cards", sanitizeFileName(code)+".json"))
+	if os.IsNotExist(err) {
+		c.cards[code] = nil // Set sem cartas no catálogo
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("erro ao ler catálogo do set %s: %w", strings.ToUpper(code), err)
+	}
+	var cards []Card
+	if err := json.Unmarshal(data, &cards); err != nil {
+		return nil, fmt.Errorf("erro ao decodificar catálogo do set %s: %w", strings.ToUpper(code), err)
+	}
+	c.cards[code] = cards
+	return cards, nil
+}
+
+// printCard procura uma impressão pelo set, número e idioma
+func (c *catalog) printCard(set, number, lang string) (*Card, error) {
+	cards, err := c.setCards(set)
+	if err != nil {
+		return nil, err
+	}
+	for i := range cards {
+		if cards[i].CollectorNumber == number && cardLang(cards[i]) == lang {
+			return &cards[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// prints retorna as impressões de uma carta no idioma, da mais recente para a mais antiga
+func (c *catalog) prints(oracleID, lang, set string) ([]Card, error) {
+	oracle := c.index.Oracle[oracleID]
+	if oracle == nil {
+		return nil, nil
+	}
+	var cards []Card
+	for _, key := range oracle.Prints {
+		code, number, _ := strings.Cut(key, "/")
+		if set != "" && !strings.EqualFold(code, set) {
+			continue
+		}
+		if lang == "any" {
+			for _, l := range languageOptions {
+				if card, err := c.printCard(code, number, l); err != nil {
+					return nil, err
+				} else if card != nil {
+					cards = append(cards, *card)
+				}
+			}
+			continue
+		}
+		card, err := c.printCard(code, number, lang)
+		if err != nil {
+			return nil, err
+		}
+		if card != nil {
+			cards = append(cards, *card)
+		}
+	}
+
+	released := make(map[string]string, len(c.index.Sets))
+	for _, s := range c.index.Sets {
+		released[s.Code] = s.ReleasedAt
+	}
+	sort.SliceStable(cards, func(i, j int) bool { return released[cards[i].Set] > released[cards[j].Set] })
+	return cards, nil
+}
+
+var catalogExactName = regexp.MustCompile(`!"([^"]*)"`)
+
+// search responde as buscas que o Downloader faz por conta própria: cartas de um set (e:),
+// impressões de uma carta (oracleid: ou !"nome") e o filtro lang:. Outras buscas devolvem
+// ok falso e seguem para a API.
+func (c *catalog) search(query string) (cards []Card, ok bool, err error) {
+	lang, set, oracleID := "en", "", ""
+	if match := catalogExactName.FindStringSubmatch(query); match != nil {
+		if oracleID = c.index.Names[strings.ToLower(match[1])]; oracleID == "" {
+			return nil, true, nil
+		}
+		query = strings.Replace(query, match[0], "", 1)
+	}
+	for _, term := range strings.Fields(query) {
+		key, value, _ := strings.Cut(term, ":")
+		switch strings.ToLower(key) {
+		case "e", "set", "s":
+			set = strings.ToLower(value)
+		case "oracleid", "oracle_id":
+			oracleID = value
+		case "lang", "language":
+			lang = strings.ToLower(value)
+		case "include", "unique", "order":
+			// Opções de apresentação que o Scryfall coloca nos search_uri
+		default:
+			return nil, false, nil
+		}
+	}
+
+	switch {
+	case oracleID != "":
+		cards, err = c.prints(oracleID, lang, set)
+	case set != "":
+		all, loadErr := c.setCards(set)
+		for _, card := range all {
+			if lang == "any" || cardLang(card) == lang {
+				cards = append(cards, card)
+			}
+		}
+		err = loadErr
+	default:
+		return nil, false, nil
+	}
+	return cards, true, err
+}
+
+// lookup responde /cards/named e /cards/<set>/<número>[/<idioma>]. ok falso indica um
+// endereço que o catálogo não conhece.
+func (c *catalog) lookup(path string, query url.Values) (card *Card, ok bool, err error) {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	if len(parts) < 2 || parts[0] != "cards" {
+		return nil, false, nil
+	}
+	for i := range parts {
+		parts[i], _ = url.PathUnescape(parts[i])
+	}
+
+	switch {
+	case parts[1] == "named":
+		card, err = c.named(query)
+	case len(parts) == 3 || len(parts) == 4:
+		lang := "en"
+		if len(parts) == 4 {
+			lang = parts[3]
+		}
+		card, err = c.printCard(parts[1], parts[2], lang)
+	default:
+		return nil, false, nil
+	}
+	if err == nil && card == nil {
+		err = errCardNotFound
+	}
+	return card, true, err
+}
+
+// named busca como /cards/named: exact exige o nome completo, fuzzy aceita parte dele
+func (c *catalog) named(query url.Values) (*Card, error) {
+	oracleID := ""
+	if name := strings.ToLower(strings.TrimSpace(query.Get("exact"))); name != "" {
+		oracleID = c.index.Names[name]
+	} else if name := normalizeCardName(query.Get("fuzzy")); name != "" {
+		matches := c.matchNames(name)
+		if len(matches) > 1 {
+			return nil, errAmbiguousCard
+		}
+		if len(matches) == 1 {
+			oracleID = matches[0]
+		}
+	}
+	if oracleID == "" {
+		return nil, nil
+	}
+
+	prints, err := c.prints(oracleID, "en", query.Get("set"))
+	if err != nil || len(prints) == 0 {
+		return nil, err
+	}
+	// Como o Scryfall, prefere a impressão mais recente em papel
+	for i := range prints {
+		if !c.isDigital(prints[i].Set) {
+			return &prints[i], nil
+		}
+	}
+	return &prints[0], nil
+}
+
+// matchNames retorna os oracle_id das cartas cujo nome (já normalizado) é igual ao texto ou,
+// se nenhum for igual, contém o texto
+func (c *catalog) matchNames(text string) []string {
+	var exact, partial []string
+	for name, id := range c.index.Names {
+		switch name = normalizeCardName(name); {
+		case name == text:
+			if !containsString(exact, id) {
+				exact = append(exact, id)
+			}
+		case strings.Contains(name, text):
+			if !containsString(partial, id) {
+				partial = append(partial, id)
+			}
+		}
+	}
+	if len(exact) > 0 {
+		return exact
+	}
+	return partial
+}
+
+// normalizeCardName deixa só letras, números e espaços simples, como a busca fuzzy recebe o nome
+func normalizeCardName(name string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(name) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			b.WriteRune(r)
+		case unicode.IsSpace(r) || r == '/' || r == ',' || r == '-':
+			b.WriteRune(' ')
+		}
+	}
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+// autocomplete sugere até 20 nomes, primeiro os que começam com o texto
+func (c *catalog) autocomplete(text string) []string {
+	text = strings.ToLower(text)
+	var prefix, contains []string
+	for _, oracle := range c.index.Oracle {
+		name := strings.ToLower(oracle.Name)
+		if strings.HasPrefix(name, text) {
+			prefix = append(prefix, oracle.Name)
+		} else if strings.Contains(name, text) {
+			contains = append(contains, oracle.Name)
+		}
+	}
+	sort.Strings(prefix)
+	sort.Strings(contains)
+	names := append(prefix, contains...)
+	return names[:min(len(names), 20)]
+}
+
+func (c *catalog) isDigital(code string) bool {
+	for _, set := range c.index.Sets {
+		if set.Code == code {
+			return set.Digital
+		}
+	}
+	return false
+}
+
+// cardLang trata cartas sem lang (arquivos antigos) como inglês
+func cardLang(card Card) string {
+	if card.Lang == "" {
+		return "en"
+	}
+	return card.Lang
+}
+
+// openCatalog abre o catálogo configurado uma única vez por pasta
+func (d *Downloader) openCatalog() (*catalog, error) {
+	d.catalogMu.Lock()
+	defer d.catalogMu.Unlock()
+	if d.catalog != nil && d.catalog.dir == d.catalogDir {
+		return d.catalog, nil
+	}
+	c, err := openCatalog(d.catalogDir)
+	if err != nil {
+		return nil, err
+	}
+	d.catalog = c
+	return c, nil
+}
+
+// catalogSearch responde uma página de busca pelo catálogo, quando ele está em uso
+func (d *Downloader) catalogSearch(searchURL string) ([]Card, bool, error) {
+	if !d.useCatalog {
+		return nil, false, nil
+	}
+	c, err := d.openCatalog()
+	if err != nil {
+		return nil, true, err
+	}
+	u, err := url.Parse(searchURL)
+	if err != nil || !strings.HasSuffix(u.Path, "/cards/search") {
+		return nil, false, nil
+	}
+	return c.search(u.Query().Get("q"))
+}
+
+// catalogCard responde uma busca de carta única pelo catálogo, quando ele está em uso
+func (d *Downloader) catalogCard(cardURL string) (*Card, bool, error) {
+	if !d.useCatalog || !strings.HasPrefix(cardURL, d.apiBase) {
+		return nil, false, nil
+	}
+	c, err := d.openCatalog()
+	if err != nil {
+		return nil, true, err
+	}
+	u, err := url.Parse(strings.TrimPrefix(cardURL, d.apiBase))
+	if err != nil {
+		return nil, false, nil
+	}
+	return c.lookup(u.Path, u.Query())
+}
@@ -0,0 +1,775 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"text/tabwriter"
+	"time"
+)
+
+// Códigos de saída da CLI
+const (
+	exitOK      = 0
+	exitFailure = 1
+	exitUsage   = 2
+
+	exitInterrupted = 130 // Ctrl+C, como no shell
+)
+
+const usageText = `Uso: mtg-downloader [opções] [comando] [argumentos]
+
+Sem comando, abre a interface interativa.
+
+Comandos:
+  sets <códigos>       Baixa os sets informados (ex: dom,war,m21 ou ALL; ALL usa -filter e aceita -preview)
+  card <nome>          Baixa todas as impressões de uma carta (-exact para o nome exato)
+  search <busca>       Baixa as cartas de uma busca do Scryfall (ex: "t:dragon r:mythic"; aceita -preview)
+  deck <arquivo>       Baixa as impressões listadas em uma decklist ("-" lê da entrada padrão)
+  jobs                 Lista os downloads interrompidos
+  jobs rm <id>         Descarta um download interrompido
+  resume [id]          Retoma um download interrompido (o mais recente se omitido)
+  update               Baixa só os sets lançados desde a última sincronização e as cartas que faltam
+  watch                Fica verificando lançamentos a cada -watch-interval minutos e baixa como o update
+  repair               Remove downloads incompletos e baixa de novo imagens corrompidas
+  list-sets [filtro]   Lista os sets disponíveis
+  catalog              Mostra o catálogo offline
+  catalog import <arq> Importa um arquivo bulk data do Scryfall (.json ou .json.gz) para o catálogo
+  cache                Mostra o resumo do cache da API
+  cache ls             Lista as respostas guardadas no cache
+  cache clear          Apaga o cache da API
+  config               Mostra a configuração em uso
+  config path          Mostra o caminho do arquivo de configuração
+  config set <k> <v>   Grava uma opção no arquivo de configuração
+
+Opções (podem vir antes ou depois do comando):
+`
+
+// cliFlags acumula as opções da linha de comando. Elas são aplicadas depois do arquivo
+// de configuração e das variáveis de ambiente, nessa ordem de prioridade.
+type cliFlags struct {
+	configPath string
+	overrides  []func(c *Config) error
+}
+
+func (f *cliFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.configPath, "config", f.configPath, "arquivo de configuração (env MTGDL_CONFIG)")
+	for _, field := range configFields {
+		field := field
+		usage := field.usage
+		if field.env != "" {
+			usage += " (env " + field.env + ")"
+		}
+		override := func(value string) error {
+			f.overrides = append(f.overrides, func(c *Config) error { return field.set(c, value) })
+			return nil
+		}
+		if field.bool {
+			fs.BoolFunc(field.flag, usage, override)
+		} else {
+			fs.Func(field.flag, usage, override)
+		}
+	}
+}
+
+// config monta a configuração efetiva: padrão, arquivo, ambiente e flags
+func (f *cliFlags) config() (Config, error) {
+	cfg, err := loadConfig(f.configPath)
+	if err != nil {
+		return cfg, err
+	}
+	if err := cfg.applyEnv(); err != nil {
+		return cfg, err
+	}
+	for _, override := range f.overrides {
+		if err := override(&cfg); err != nil {
+			return cfg, err
+		}
+	}
+	return cfg, cfg.validate()
+}
+
+// run interpreta a linha de comando e retorna o código de saída
+func run(args []string) int {
+	flags := &cliFlags{configPath: defaultConfigPath()}
+	fs := newFlagSet("mtg-downloader", flags)
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return exitOK
+		}
+		return exitUsage
+	}
+
+	if fs.NArg() == 0 {
+		cfg, err := flags.config()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Erro: %v\n", err)
+			return exitUsage
+		}
+		return runTUI(cfg, flags.configPath)
+	}
+
+	// Ctrl+C cancela o comando em qualquer etapa, inclusive as consultas antes do download
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+
+	command, commandArgs := fs.Arg(0), fs.Args()[1:]
+	switch command {
+	case "sets":
+		return cmdSets(ctx, flags, commandArgs)
+	case "card":
+		return cmdCard(ctx, flags, commandArgs)
+	case "search":
+		return cmdSearch(ctx, flags, commandArgs)
+	case "deck":
+		return cmdDeck(ctx, flags, commandArgs)
+	case "jobs":
+		return cmdJobs(flags, commandArgs)
+	case "resume":
+		return cmdResume(ctx, flags, commandArgs)
+	case "update":
+		return cmdUpdate(ctx, flags, commandArgs)
+	case "watch":
+		return cmdWatch(ctx, flags, commandArgs)
+	case "repair":
+		return cmdRepair(ctx, flags, commandArgs)
+	case "list-sets":
+		return cmdListSets(ctx, flags, commandArgs)
+	case "catalog":
+		return cmdCatalog(ctx, flags, commandArgs)
+	case "cache":
+		return cmdCache(flags, commandArgs)
+	case "config":
+		return cmdConfig(flags, commandArgs)
+	case "help":
+		fs.Usage()
+		return exitOK
+	default:
+		fmt.Fprintf(os.Stderr, "Comando desconhecido: %s\n\n", command)
+		fs.Usage()
+		return exitUsage
+	}
+}
+
+// newFlagSet cria um FlagSet com as opções comuns a todos os comandos
+func newFlagSet(name string, flags *cliFlags) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.register(fs)
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), usageText)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// parseCommand lê as opções de um subcomando, aceitando-as misturadas aos argumentos posicionais,
+// e devolve a configuração resultante. setup registra opções próprias do subcomando (pode ser nil).
+// Depois de "--" tudo é posicional, inclusive nomes que começam com "-".
+func parseCommand(name string, flags *cliFlags, args []string, setup func(fs *flag.FlagSet)) ([]string, Config, bool) {
+	fs := newFlagSet(name, flags)
+	if setup != nil {
+		setup(fs)
+	}
+	positional, ok := parseInterspersed(fs, args)
+	if !ok {
+		return nil, Config{}, false
+	}
+
+	cfg, err := flags.config()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Erro: %v\n", err)
+		return nil, cfg, false
+	}
+	return positional, cfg, true
+}
+
+// parseInterspersed chama fs.Parse de novo a cada argumento posicional, já que o pacote flag para
+// no primeiro. Quando o último argumento consumido pelo Parse é "--", o restante vai inteiro
+// para os posicionais sem ser interpretado.
+func parseInterspersed(fs *flag.FlagSet, args []string) ([]string, bool) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, false
+		}
+		rest := fs.Args()
+		if consumed := len(args) - len(rest); consumed > 0 && args[consumed-1] == "--" {
+			return append(positional, rest...), true
+		}
+		if len(rest) == 0 {
+			return positional, true
+		}
+		positional = append(positional, rest[0])
+		args = rest[1:]
+	}
+}
+
+func newCLIDownloader(cfg Config) *Downloader {
+	d := NewDownloader(cfg)
+	d.logOutput = os.Stderr
+	return d
+}
+
+// withProgress executa fn imprimindo o progresso do Downloader periodicamente. ctx é o contexto
+// do comando, cancelado com Ctrl+C, o que interrompe o download sem deixar arquivos temporários.
+func withProgress(ctx context.Context, d *Downloader, fn func(ctx context.Context)) {
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(2 * time.Second)
+		defer ticker.Stop()
+		last := int64(-1)
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				current := atomic.LoadInt64(&d.completedTasks)
+				total := atomic.LoadInt64(&d.totalTasks)
+				if total > 0 && current != last {
+					d.logf("Progresso: %d/%d (%.1f%%)", current, total, float64(current)/float64(total)*100)
+					last = current
+				}
+			}
+		}
+	}()
+	fn(ctx)
+	close(stop)
+	<-done
+}
+
+// fetchFailed mostra o erro de uma consulta feita antes do download e devolve o código de saída,
+// 130 quando ela foi interrompida com Ctrl+C
+func fetchFailed(ctx context.Context, err error) int {
+	if ctx.Err() != nil {
+		fmt.Fprintln(os.Stderr, "Cancelado")
+		return exitInterrupted
+	}
+	fmt.Fprintf(os.Stderr, "Erro: %v\n", err)
+	return exitFailure
+}
+
+// printResult mostra o resumo do download e devolve o código de saída correspondente
+func printResult(d *Downloader, result downloadCompleteMsg) int {
+	d.logf("%s", result.message)
+	if retries := atomic.LoadInt64(&d.retryCount); retries > 0 {
+		d.logf("Retentativas: %d", retries)
+	}
+	if len(result.completed) > 0 {
+		d.logf("Concluídos (%d): %s", len(result.completed), strings.ToUpper(strings.Join(result.completed, ", ")))
+	}
+	if result.variantsKept > 0 || result.variantsCollapsed > 0 {
+		d.logf("Variantes: %d mantidas, %d descartadas", result.variantsKept, result.variantsCollapsed)
+	}
+	if len(result.skipped) > 0 {
+		d.logf("Ignorados (%d):", len(result.skipped))
+		for _, line := range result.skipped {
+			d.logf("  %s", line)
+		}
+	}
+	if len(result.failed) > 0 {
+		d.logf("Com falha (%d): %s", len(result.failed), strings.ToUpper(strings.Join(result.failed, ", ")))
+	}
+	if result.cancelled {
+		d.logf("Download cancelado")
+		return exitInterrupted
+	}
+	if len(result.failed) > 0 {
+		return exitFailure
+	}
+	if !result.success {
+		return exitFailure
+	}
+	return exitOK
+}
+
+func cmdSets(ctx context.Context, flags *cliFlags, args []string) int {
+	var preview bool
+	positional, cfg, ok := parseCommand("sets", flags, args, func(fs *flag.FlagSet) {
+		fs.BoolVar(&preview, "preview", false, "com ALL, apenas lista os sets que o filtro pega")
+	})
+	if !ok {
+		return exitUsage
+	}
+	codes := parseSetCodes(strings.Join(positional, ","))
+	if len(codes) == 0 {
+		fmt.Fprintln(os.Stderr, "Informe ao menos um código de set (ex: sets dom,war,m21)")
+		return exitUsage
+	}
+
+	d := newCLIDownloader(cfg)
+	sets, err := d.fetchSets(ctx)
+	if err != nil {
+		return fetchFailed(ctx, err)
+	}
+
+	if len(codes) == 1 && strings.ToUpper(codes[0]) == "ALL" {
+		filter, _ := parseSetFilter(cfg.AllFilter) // já validado em cfg.validate
+		matched, cards := filter.apply(sets)
+		if preview {
+			printSetTable(matched)
+			fmt.Fprintf(os.Stderr, "%d sets, %d cartas\n", len(matched), cards)
+			return exitOK
+		}
+		if len(matched) == 0 {
+			fmt.Fprintf(os.Stderr, "Erro: nenhum set corresponde ao filtro %q\n", cfg.AllFilter)
+			return exitFailure
+		}
+		codes = setCodes(matched)
+		d.logf("Iniciando download de TODOS os sets (%d sets, %d cartas)", len(codes), cards)
+	} else {
+		d.logf("Iniciando download de %d sets: %s", len(codes), strings.Join(codes, ", "))
+	}
+
+	var result downloadCompleteMsg
+	withProgress(ctx, d, func(ctx context.Context) { result = d.downloadSets(ctx, sets, codes) })
+	return printResult(d, result)
+}
+
+func cmdCard(ctx context.Context, flags *cliFlags, args []string) int {
+	var exact bool
+	positional, cfg, ok := parseCommand("card", flags, args, func(fs *flag.FlagSet) {
+		fs.BoolVar(&exact, "exact", false, "usa o nome exato em vez da busca aproximada")
+	})
+	if !ok {
+		return exitUsage
+	}
+	cardName := strings.TrimSpace(strings.Join(positional, " "))
+	if cardName == "" {
+		fmt.Fprintln(os.Stderr, "Informe o nome da carta (ex: card \"Lightning Bolt\")")
+		return exitUsage
+	}
+
+	d := newCLIDownloader(cfg)
+	var result downloadCompleteMsg
+	var err error
+	withProgress(ctx, d, func(ctx context.Context) { result, err = d.downloadCard(ctx, cardName, exact) })
+	if errors.Is(err, errAmbiguousCard) {
+		fmt.Fprintf(os.Stderr, "Erro: %v\n", err)
+		if names, _ := d.autocomplete(ctx, cardName); len(names) > 0 {
+			fmt.Fprintln(os.Stderr, "Você quis dizer (use -exact com um destes nomes):")
+			for _, name := range names {
+				fmt.Fprintf(os.Stderr, "  %s\n", name)
+			}
+		}
+		return exitFailure
+	}
+	if err != nil {
+		return fetchFailed(ctx, err)
+	}
+	return printResult(d, result)
+}
+
+func cmdSearch(ctx context.Context, flags *cliFlags, args []string) int {
+	var preview bool
+	positional, cfg, ok := parseCommand("search", flags, args, func(fs *flag.FlagSet) {
+		fs.BoolVar(&preview, "preview", false, "apenas mostra quantas cartas a busca encontra")
+	})
+	if !ok {
+		return exitUsage
+	}
+	query := strings.TrimSpace(strings.Join(positional, " "))
+	if query == "" {
+		fmt.Fprintln(os.Stderr, "Informe a busca (ex: search \"t:dragon r:mythic year>=2021\")")
+		return exitUsage
+	}
+
+	d := newCLIDownloader(cfg)
+	total, err := d.countSearch(ctx, query)
+	if err != nil {
+		return fetchFailed(ctx, err)
+	}
+	if preview || total == 0 {
+		fmt.Fprintf(os.Stderr, "%d cartas encontradas\n", total)
+		return exitOK
+	}
+
+	d.logf("Iniciando download da busca '%s'", query)
+	var result downloadCompleteMsg
+	withProgress(ctx, d, func(ctx context.Context) { result = d.downloadSearch(ctx, query) })
+	return printResult(d, result)
+}
+
+func cmdDeck(ctx context.Context, flags *cliFlags, args []string) int {
+	var format string
+	positional, cfg, ok := parseCommand("deck", flags, args, func(fs *flag.FlagSet) {
+		fs.StringVar(&format, "format", "", "formato da decklist ("+strings.Join(deckFormatNames(), ", ")+"); vazio detecta automaticamente")
+	})
+	if !ok {
+		return exitUsage
+	}
+	if len(positional) != 1 {
+		fmt.Fprintln(os.Stderr, "Informe o arquivo da decklist (ex: deck burn.txt)")
+		return exitUsage
+	}
+
+	deck, err := readDecklist(positional[0], format)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Erro: %v\n", err)
+		return exitFailure
+	}
+	if len(deck.Entries) == 0 {
+		fmt.Fprintln(os.Stderr, "Nenhuma carta encontrada na decklist")
+		return exitFailure
+	}
+
+	d := newCLIDownloader(cfg)
+	d.logf("Decklist (%s) com %d cartas", deck.Format, len(deck.Entries))
+	var result downloadCompleteMsg
+	withProgress(ctx, d, func(ctx context.Context) { result = d.downloadDeck(ctx, deck) })
+	return printResult(d, result)
+}
+
+func cmdJobs(flags *cliFlags, args []string) int {
+	positional, cfg, ok := parseCommand("jobs", flags, args, nil)
+	if !ok {
+		return exitUsage
+	}
+
+	if len(positional) > 0 {
+		if positional[0] != "rm" || len(positional) != 2 {
+			fmt.Fprintln(os.Stderr, "Uso: jobs [rm <id>]")
+			return exitUsage
+		}
+		job, err := findJob(cfg.DownloadDir, positional[1])
+		if err == nil {
+			err = job.remove()
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Erro: %v\n", err)
+			return exitFailure
+		}
+		fmt.Fprintf(os.Stderr, "Job %s descartado\n", job.ID)
+		return exitOK
+	}
+
+	jobs, err := listJobs(cfg.DownloadDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Erro: %v\n", err)
+		return exitFailure
+	}
+	if len(jobs) == 0 {
+		fmt.Fprintln(os.Stderr, "Nenhum download interrompido")
+		return exitOK
+	}
+	for _, job := range jobs {
+		fmt.Println(job.summary())
+	}
+	return exitOK
+}
+
+func cmdResume(ctx context.Context, flags *cliFlags, args []string) int {
+	positional, cfg, ok := parseCommand("resume", flags, args, nil)
+	if !ok {
+		return exitUsage
+	}
+	if len(positional) > 1 {
+		fmt.Fprintln(os.Stderr, "Uso: resume [id]")
+		return exitUsage
+	}
+	id := ""
+	if len(positional) == 1 {
+		id = positional[0]
+	}
+
+	job, err := findJob(cfg.DownloadDir, id)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Erro: %v\n", err)
+		return exitFailure
+	}
+
+	d := newCLIDownloader(cfg)
+	d.logf("Job %s: %s", job.ID, job.Description)
+	var result downloadCompleteMsg
+	withProgress(ctx, d, func(ctx context.Context) { result = d.resumeJob(ctx, job, nil) })
+	return printResult(d, result)
+}
+
+func cmdUpdate(ctx context.Context, flags *cliFlags, args []string) int {
+	var dryRun, full bool
+	var since string
+	_, cfg, ok := parseCommand("update", flags, args, func(fs *flag.FlagSet) {
+		fs.BoolVar(&dryRun, "dry-run", false, "apenas lista os sets que seriam baixados")
+		fs.BoolVar(&full, "full", false, "confere as imagens de todos os sets já baixados")
+		fs.StringVar(&since, "since", "", "considera novos os sets lançados a partir desta data (AAAA-MM-DD)")
+	})
+	if !ok {
+		return exitUsage
+	}
+	if since != "" {
+		if _, err := time.Parse("2006-01-02", since); err != nil {
+			fmt.Fprintf(os.Stderr, "Data inválida em -since: %s (use AAAA-MM-DD)\n", since)
+			return exitUsage
+		}
+	}
+
+	d := newCLIDownloader(cfg)
+	defer d.revalidateCache()()
+	sets, err := d.fetchSets(ctx)
+	if err != nil {
+		return fetchFailed(ctx, err)
+	}
+	filter, _ := parseSetFilter(cfg.AllFilter) // já validado em cfg.validate
+	plan, err := d.planUpdate(sets, filter, since, full)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Erro: %v\n", err)
+		return exitFailure
+	}
+	d.logf("Sets novos desde %s: %d | sets a conferir: %d | sem mudanças: %d", plan.baseline, len(plan.newSets), len(plan.changed), plan.unchanged)
+	if dryRun {
+		printSetTable(append(plan.newSets, plan.changed...))
+		return exitOK
+	}
+
+	var result updateResult
+	withProgress(ctx, d, func(ctx context.Context) { result = d.runUpdate(ctx, sets, plan) })
+	if lines := result.changelog(); len(lines) > 0 {
+		for _, line := range lines {
+			fmt.Println(line)
+		}
+	} else if !result.cancelled {
+		fmt.Println("Nenhuma imagem nova")
+	}
+	return printResult(d, result.downloadCompleteMsg)
+}
+
+func cmdWatch(ctx context.Context, flags *cliFlags, args []string) int {
+	var since string
+	_, cfg, ok := parseCommand("watch", flags, args, func(fs *flag.FlagSet) {
+		fs.StringVar(&since, "since", "", "na primeira verificação, considera novos os sets lançados a partir desta data (AAAA-MM-DD)")
+	})
+	if !ok {
+		return exitUsage
+	}
+	if since != "" {
+		if _, err := time.Parse("2006-01-02", since); err != nil {
+			fmt.Fprintf(os.Stderr, "Data inválida em -since: %s (use AAAA-MM-DD)\n", since)
+			return exitUsage
+		}
+	}
+
+	logPath := watchLogPath(cfg)
+	if err := os.MkdirAll(filepath.Dir(logPath), 0755); err != nil {
+		fmt.Fprintf(os.Stderr, "Erro: %v\n", err)
+		return exitFailure
+	}
+	logFile, err := os.OpenFile(logPath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Erro ao abrir o log do watch: %v\n", err)
+		return exitFailure
+	}
+	defer logFile.Close()
+
+	d := newCLIDownloader(cfg)
+	d.logOutput = io.MultiWriter(os.Stderr, &timestampWriter{w: logFile})
+	filter, _ := parseSetFilter(cfg.AllFilter) // já validado em cfg.validate
+	interval := time.Duration(cfg.WatchIntervalMinutes) * time.Minute
+	d.logf("Watch iniciado: verificando a cada %d minutos, log em %s", cfg.WatchIntervalMinutes, logPath)
+
+	var interrupted bool
+	withProgress(ctx, d, func(ctx context.Context) {
+		err = d.watch(ctx, filter, since, interval)
+		interrupted = ctx.Err() != nil
+	})
+	if err != nil {
+		d.logf("Erro: %v", err)
+		return exitFailure
+	}
+	d.logf("Watch encerrado")
+	if interrupted {
+		return exitInterrupted
+	}
+	return exitOK
+}
+
+func cmdRepair(ctx context.Context, flags *cliFlags, args []string) int {
+	var dryRun bool
+	_, cfg, ok := parseCommand("repair", flags, args, func(fs *flag.FlagSet) {
+		fs.BoolVar(&dryRun, "dry-run", false, "apenas lista os arquivos com problema")
+	})
+	if !ok {
+		return exitUsage
+	}
+
+	d := newCLIDownloader(cfg)
+	var sets []Set
+	if !dryRun {
+		var err error
+		if sets, err = d.fetchSets(ctx); err != nil {
+			return fetchFailed(ctx, err)
+		}
+	}
+
+	var result downloadCompleteMsg
+	withProgress(ctx, d, func(ctx context.Context) { result = d.repairImages(ctx, sets, dryRun) })
+	return printResult(d, result)
+}
+
+func cmdListSets(ctx context.Context, flags *cliFlags, args []string) int {
+	positional, cfg, ok := parseCommand("list-sets", flags, args, nil)
+	if !ok {
+		return exitUsage
+	}
+
+	d := newCLIDownloader(cfg)
+	sets, err := d.fetchSets(ctx)
+	if err != nil {
+		return fetchFailed(ctx, err)
+	}
+
+	printSetTable(filterSets(sets, strings.Join(positional, " ")))
+	return exitOK
+}
+
+// printSetTable imprime os sets em colunas na saída padrão
+func printSetTable(sets []Set) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "CÓDIGO\tNOME\tTIPO\tCARTAS\tLANÇAMENTO\tDIGITAL")
+	for _, set := range sets {
+		digital := ""
+		if set.Digital {
+			digital = "sim"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\t%s\n", strings.ToUpper(set.Code), set.Name, set.SetType, set.CardCount, set.ReleasedAt, digital)
+	}
+	w.Flush()
+}
+
+func cmdCatalog(ctx context.Context, flags *cliFlags, args []string) int {
+	positional, cfg, ok := parseCommand("catalog", flags, args, nil)
+	if !ok {
+		return exitUsage
+	}
+
+	if len(positional) == 0 {
+		c, err := openCatalog(cfg.CatalogDir)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Erro: %v\n", err)
+			return exitFailure
+		}
+		fmt.Printf("Pasta: %s\n", cfg.CatalogDir)
+		fmt.Printf("Origem: %s (importado em %s)\n", c.index.Source, c.index.ImportedAt.Format("2006-01-02 15:04"))
+		fmt.Printf("%d sets, %d cartas, %d impressões\n", len(c.index.Sets), len(c.index.Oracle), c.index.Cards)
+		fmt.Printf("Em uso: %s\n", yesNo(cfg.UseCatalog))
+		return exitOK
+	}
+	if positional[0] != "import" || len(positional) != 2 {
+		fmt.Fprintln(os.Stderr, "Uso: catalog [import <arquivo bulk data>]")
+		return exitUsage
+	}
+
+	fmt.Fprintf(os.Stderr, "Importando %s para %s\n", positional[1], cfg.CatalogDir)
+	index, err := importCatalog(ctx, positional[1], cfg.CatalogDir, func(cards int) {
+		fmt.Fprintf(os.Stderr, "%d cartas lidas\n", cards)
+	})
+	if ctx.Err() != nil {
+		fmt.Fprintln(os.Stderr, "Importação cancelada; o catálogo anterior foi mantido")
+		return exitInterrupted
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Erro: %v\n", err)
+		return exitFailure
+	}
+	fmt.Fprintf(os.Stderr, "Catálogo importado: %d sets, %d cartas, %d impressões\n", len(index.Sets), len(index.Oracle), index.Cards)
+	if !cfg.UseCatalog {
+		fmt.Fprintln(os.Stderr, "Para usá-lo, ligue use_catalog (flag -use-catalog ou: config set use_catalog true)")
+	}
+	return exitOK
+}
+
+func cmdCache(flags *cliFlags, args []string) int {
+	positional, cfg, ok := parseCommand("cache", flags, args, nil)
+	if !ok {
+		return exitUsage
+	}
+	if len(positional) > 1 {
+		fmt.Fprintln(os.Stderr, "Uso: cache [ls | clear]")
+		return exitUsage
+	}
+	cache := &httpCache{dir: cfg.CacheDir, ttl: time.Duration(cfg.CacheTTLMinutes) * time.Minute}
+
+	subcommand := ""
+	if len(positional) == 1 {
+		subcommand = positional[0]
+	}
+	switch subcommand {
+	case "clear":
+		if err := cache.clear(); err != nil {
+			fmt.Fprintf(os.Stderr, "Erro: %v\n", err)
+			return exitFailure
+		}
+		fmt.Fprintf(os.Stderr, "Cache apagado: %s\n", cfg.CacheDir)
+		return exitOK
+	case "", "ls":
+	default:
+		fmt.Fprintf(os.Stderr, "Subcomando de cache desconhecido: %s (use ls ou clear)\n", subcommand)
+		return exitUsage
+	}
+
+	entries, size, err := cache.entries()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Erro: %v\n", err)
+		return exitFailure
+	}
+	if subcommand == "ls" {
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "IDADE\tTAMANHO\tVÁLIDA\tURL")
+		for _, entry := range entries {
+			fmt.Fprintf(w, "%s\t%d\t%s\t%s\n", time.Since(entry.StoredAt).Round(time.Second), len(entry.Body), yesNo(cache.fresh(&entry)), entry.URL)
+		}
+		w.Flush()
+		return exitOK
+	}
+
+	expired := 0
+	for i := range entries {
+		if !cache.fresh(&entries[i]) {
+			expired++
+		}
+	}
+	fmt.Printf("Pasta: %s\n", cfg.CacheDir)
+	fmt.Printf("Ligado: %s | validade: %d min | offline: %s\n", yesNo(cfg.Cache), cfg.CacheTTLMinutes, yesNo(cfg.Offline))
+	fmt.Printf("%d respostas (%d expiradas), %.1f MB\n", len(entries), expired, float64(size)/(1<<20))
+	return exitOK
+}
+
+func cmdConfig(flags *cliFlags, args []string) int {
+	positional, cfg, ok := parseCommand("config", flags, args, nil)
+	if !ok {
+		return exitUsage
+	}
+
+	if len(positional) == 0 {
+		for _, field := range configFields {
+			fmt.Printf("%s=%s\n", field.key, field.get(&cfg))
+		}
+		return exitOK
+	}
+
+	switch positional[0] {
+	case "path":
+		fmt.Println(flags.configPath)
+	case "set":
+		if len(positional) != 3 {
+			fmt.Fprintln(os.Stderr, "Uso: config set <opção> <valor>")
+			return exitUsage
+		}
+		if err := persistSetting(flags.configPath, positional[1], positional[2]); err != nil {
+			fmt.Fprintf(os.Stderr, "Erro: %v\n", err)
+			return exitFailure
+		}
+		fmt.Fprintf(os.Stderr, "%s salvo em %s\n", positional[1], flags.configPath)
+	default:
+		fmt.Fprintf(os.Stderr, "Subcomando de config desconhecido: %s (use path ou set)\n", positional[0])
+		return exitUsage
+	}
+	return exitOK
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseCommand(t *testing.T) {
+	// Variáveis MTGDL_* do ambiente de quem roda os testes não devem interferir
+	for _, field := range configFields {
+		if field.env != "" {
+			t.Setenv(field.env, "")
+		}
+	}
+
+	tests := []struct {
+		args        []string
+		wantArgs    []string
+		wantDir     string
+		wantPreview bool
+	}{
+		{args: []string{"dom", "war"}, wantArgs: []string{"dom", "war"}},
+		{args: []string{"dom", "-dir", "/srv/cartas", "war"}, wantArgs: []string{"dom", "war"}, wantDir: "/srv/cartas"},
+		{args: []string{"ALL", "-preview"}, wantArgs: []string{"ALL"}, wantPreview: true},
+		{args: []string{"-preview", "-dir=/srv", "ALL"}, wantArgs: []string{"ALL"}, wantDir: "/srv", wantPreview: true},
+		// Depois de "--" nada é opção, nem nomes que começam com "-"
+		{args: []string{"--", "-burn.txt"}, wantArgs: []string{"-burn.txt"}},
+		{args: []string{"dom", "--", "-preview", "-dir", "/srv"}, wantArgs: []string{"dom", "-preview", "-dir", "/srv"}},
+		{args: []string{"-dir", "/srv", "dom", "--", "--"}, wantArgs: []string{"dom", "--"}, wantDir: "/srv"},
+		{args: []string{"--"}, wantArgs: nil},
+	}
+	for _, tt := range tests {
+		flags := &cliFlags{configPath: filepath.Join(t.TempDir(), "config.json")}
+		var preview bool
+		got, cfg, ok := parseCommand("sets", flags, tt.args, func(fs *flag.FlagSet) {
+			fs.BoolVar(&preview, "preview", false, "")
+		})
+		if !ok {
+			t.Errorf("parseCommand(%q) falhou", tt.args)
+			continue
+		}
+		wantDir := tt.wantDir
+		if wantDir == "" {
+			wantDir = defaultConfig().DownloadDir
+		}
+		if !reflect.DeepEqual(got, tt.wantArgs) || cfg.DownloadDir != wantDir || preview != tt.wantPreview {
+			t.Errorf("parseCommand(%q) = %q, dir %q, preview %v; esperado %q, %q, %v",
+				tt.args, got, cfg.DownloadDir, preview, tt.wantArgs, wantDir, tt.wantPreview)
+		}
+	}
+}
+
+func TestParseInterspersedUnknownFlag(t *testing.T) {
+	fs := flag.NewFlagSet("sets", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	if _, ok := parseInterspersed(fs, []string{"dom", "-nope"}); ok {
+		t.Error("opção desconhecida depois de um posicional deveria falhar")
+	}
+}
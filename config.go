@@ -0,0 +1,471 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Config reúne as opções que controlam o Downloader
+type Config struct {
+	DownloadDir string `json:"download_dir"`
+	Quality     string `json:"quality"`
+	MaxWorkers  int    `json:"max_workers"`
+	APIBase     string `json:"api_url"`
+
+	NameTemplate string `json:"name_template"`
+	Preset       string `json:"preset"`   // layout de um cliente de jogo; vazio usa NameTemplate
+	Variants     string `json:"variants"` // all ou first
+	FaceNames    string `json:"face_names"`
+	CardBack     bool   `json:"card_back"`
+	CardBackURL  string `json:"card_back_url"`
+
+	AllFilter string `json:"all_filter"` // expressão de filtro padrão para ALL
+
+	IncludeRelated bool   `json:"related"`
+	RelatedFolder  string `json:"related_folder"` // subpasta dentro da pasta do set; vazio grava junto
+
+	Lang         string `json:"lang"`          // idioma das impressões (en, pt, ja...)
+	LangFallback bool   `json:"lang_fallback"` // usa a impressão em inglês quando não há no idioma
+
+	CatalogDir string `json:"catalog_dir"` // catálogo importado de um arquivo bulk data
+	UseCatalog bool   `json:"use_catalog"` // consulta o catálogo em vez da API
+
+	Cache           bool   `json:"cache"` // guarda as respostas da API em disco
+	CacheDir        string `json:"cache_dir"`
+	CacheTTLMinutes int    `json:"cache_ttl_minutes"` // validade antes de revalidar; 0 revalida sempre. Sugestões de nome não são guardadas
+	Offline         bool   `json:"offline"`           // só usa o cache, sem nenhuma requisição
+
+	WatchIntervalMinutes int    `json:"watch_interval_minutes"` // intervalo entre as verificações do watch
+	WatchLog             string `json:"watch_log"`              // log dos ciclos do watch; vazio usa <download_dir>/.watch.log
+
+	MaxRetries   int `json:"max_retries"`
+	RetryDelayMs int `json:"retry_delay_ms"`
+
+	APIRate   float64 `json:"api_rate"`   // requisições por segundo à API, 0 = sem limite
+	ImageRate float64 `json:"image_rate"` // downloads de imagem por segundo, 0 = sem limite
+}
+
+// Tipos de imagem do Scryfall. Quality aceita vários separados por vírgula, baixados na mesma execução.
+var qualityOptions = []string{"small", "normal", "large", "png", "art_crop", "border_crop"}
+
+func defaultConfig() Config {
+	return Config{
+		DownloadDir: "./downloads",
+		Quality:     "large",
+		MaxWorkers:  10,
+		APIBase:     defaultAPIBase,
+
+		NameTemplate: defaultNameTemplate,
+		Variants:     "first",
+		FaceNames:    "face",
+		CardBackURL:  defaultCardBackURL,
+
+		RelatedFolder: "tokens",
+
+		Lang:         "en",
+		LangFallback: true,
+
+		CatalogDir: defaultCachePath("catalog"),
+
+		Cache:           true,
+		CacheDir:        defaultCachePath("http"),
+		CacheTTLMinutes: 720,
+
+		WatchIntervalMinutes: 60,
+
+		MaxRetries:   3,
+		RetryDelayMs: 1000,
+
+		APIRate:   10,
+		ImageRate: 20,
+	}
+}
+
+// configField descreve uma opção da configuração e como ela aparece no arquivo,
+// na linha de comando e no ambiente
+type configField struct {
+	key   string // nome no arquivo e em "config set"
+	flag  string
+	env   string
+	usage string
+	bool  bool // flag sem valor (-card-back equivale a -card-back=true)
+	get   func(c *Config) string
+	set   func(c *Config, value string) error
+}
+
+var configFields = []configField{
+	{
+		key: "download_dir", flag: "dir", env: "MTGDL_DIR", usage: "pasta de download",
+		get: func(c *Config) string { return c.DownloadDir },
+		set: func(c *Config, v string) error { c.DownloadDir = v; return nil },
+	},
+	{
+		key: "quality", flag: "quality", env: "MTGDL_QUALITY", usage: "tipos de imagem separados por vírgula (small, normal, large, png, art_crop, border_crop)",
+		get: func(c *Config) string { return c.Quality },
+		set: func(c *Config, v string) error { c.Quality = v; return nil },
+	},
+	{
+		key: "max_workers", flag: "workers", env: "MTGDL_WORKERS", usage: "downloads simultâneos (1-50)",
+		get: func(c *Config) string { return strconv.Itoa(c.MaxWorkers) },
+		set: func(c *Config, v string) error { return setInt(&c.MaxWorkers, v) },
+	},
+	{
+		key: "api_url", flag: "api-url", env: "MTGDL_API_URL", usage: "endereço da API do Scryfall ou de um espelho",
+		get: func(c *Config) string { return c.APIBase },
+		set: func(c *Config, v string) error { c.APIBase = v; return nil },
+	},
+	{
+		key: "name_template", flag: "name-template", env: "MTGDL_NAME_TEMPLATE", usage: "caminho de cada imagem na pasta de download, sem extensão (placeholders {set}, {collector_number}, {name}, {face}, {lang}, {quality}, {rarity}, {artist})",
+		get: func(c *Config) string { return c.NameTemplate },
+		set: func(c *Config, v string) error { c.NameTemplate = v; return nil },
+	},
+	{
+		key: "preset", flag: "preset", env: "MTGDL_PRESET", usage: "layout de um cliente de jogo (" + strings.Join(exportPresetNames(), ", ") + "); vazio usa -name-template",
+		get: func(c *Config) string { return c.Preset },
+		set: func(c *Config, v string) error { c.Preset = v; return nil },
+	},
+	{
+		key: "variants", flag: "variants", env: "MTGDL_VARIANTS", usage: "artes alternativas no mesmo set: all mantém todas, first só a primeira",
+		get: func(c *Config) string { return c.Variants },
+		set: func(c *Config, v string) error { c.Variants = v; return nil },
+	},
+	{
+		key: "face_names", flag: "face-names", env: "MTGDL_FACE_NAMES", usage: "nome das faces de cartas de dupla face: face usa o nome de cada face, paired usa <carta>_front e <carta>_back",
+		get: func(c *Config) string { return c.FaceNames },
+		set: func(c *Config, v string) error { c.FaceNames = v; return nil },
+	},
+	{
+		key: "card_back", flag: "card-back", env: "MTGDL_CARD_BACK", usage: "baixa o verso padrão uma vez em cada pasta", bool: true,
+		get: func(c *Config) string { return strconv.FormatBool(c.CardBack) },
+		set: func(c *Config, v string) error { return setBool(&c.CardBack, v) },
+	},
+	{
+		key: "card_back_url", flag: "card-back-url", env: "MTGDL_CARD_BACK_URL", usage: "imagem usada como verso padrão",
+		get: func(c *Config) string { return c.CardBackURL },
+		set: func(c *Config, v string) error { c.CardBackURL = v; return nil },
+	},
+	{
+		key: "all_filter", flag: "filter", env: "MTGDL_ALL_FILTER", usage: "filtro dos sets pegos por ALL (ex: \"type:expansion,core from:2015 min:100\")",
+		get: func(c *Config) string { return c.AllFilter },
+		set: func(c *Config, v string) error { c.AllFilter = v; return nil },
+	},
+	{
+		key: "related", flag: "related", env: "MTGDL_RELATED", usage: "inclui tokens, emblemas e art series ligados aos sets", bool: true,
+		get: func(c *Config) string { return strconv.FormatBool(c.IncludeRelated) },
+		set: func(c *Config, v string) error { return setBool(&c.IncludeRelated, v) },
+	},
+	{
+		key: "related_folder", flag: "related-folder", env: "MTGDL_RELATED_FOLDER", usage: "subpasta dos tokens dentro da pasta do set; vazio grava junto com as cartas",
+		get: func(c *Config) string { return c.RelatedFolder },
+		set: func(c *Config, v string) error { c.RelatedFolder = v; return nil },
+	},
+	{
+		key: "lang", flag: "lang", env: "MTGDL_LANG", usage: "idioma das impressões (" + strings.Join(languageOptions, ", ") + ")",
+		get: func(c *Config) string { return c.Lang },
+		set: func(c *Config, v string) error { c.Lang = strings.ToLower(strings.TrimSpace(v)); return nil },
+	},
+	{
+		key: "lang_fallback", flag: "lang-fallback", env: "MTGDL_LANG_FALLBACK", usage: "baixa em inglês as cartas sem impressão no idioma escolhido", bool: true,
+		get: func(c *Config) string { return strconv.FormatBool(c.LangFallback) },
+		set: func(c *Config, v string) error { return setBool(&c.LangFallback, v) },
+	},
+	{
+		key: "catalog_dir", flag: "catalog-dir", env: "MTGDL_CATALOG_DIR", usage: "pasta do catálogo offline (criado com catalog import)",
+		get: func(c *Config) string { return c.CatalogDir },
+		set: func(c *Config, v string) error { c.CatalogDir = v; return nil },
+	},
+	{
+		key: "use_catalog", flag: "use-catalog", env: "MTGDL_USE_CATALOG", usage: "responde sets e cartas pelo catálogo offline; só as imagens usam a rede", bool: true,
+		get: func(c *Config) string { return strconv.FormatBool(c.UseCatalog) },
+		set: func(c *Config, v string) error { return setBool(&c.UseCatalog, v) },
+	},
+	{
+		key: "cache", flag: "cache", env: "MTGDL_CACHE", usage: "guarda as respostas da API em disco (-cache=false desliga)", bool: true,
+		get: func(c *Config) string { return strconv.FormatBool(c.Cache) },
+		set: func(c *Config, v string) error { return setBool(&c.Cache, v) },
+	},
+	{
+		key: "cache_dir", flag: "cache-dir", env: "MTGDL_CACHE_DIR", usage: "pasta do cache da API",
+		get: func(c *Config) string { return c.CacheDir },
+		set: func(c *Config, v string) error { c.CacheDir = v; return nil },
+	},
+	{
+		key: "cache_ttl_minutes", flag: "cache-ttl", env: "MTGDL_CACHE_TTL", usage: "minutos em que uma resposta do cache vale sem revalidar (0 revalida sempre)",
+		get: func(c *Config) string { return strconv.Itoa(c.CacheTTLMinutes) },
+		set: func(c *Config, v string) error { return setInt(&c.CacheTTLMinutes, v) },
+	},
+	{
+		key: "offline", flag: "offline", env: "MTGDL_OFFLINE", usage: "responde só com o cache e o catálogo, sem acessar a rede", bool: true,
+		get: func(c *Config) string { return strconv.FormatBool(c.Offline) },
+		set: func(c *Config, v string) error { return setBool(&c.Offline, v) },
+	},
+	{
+		key: "watch_interval_minutes", flag: "watch-interval", env: "MTGDL_WATCH_INTERVAL", usage: "minutos entre as verificações do watch (1-10080)",
+		get: func(c *Config) string { return strconv.Itoa(c.WatchIntervalMinutes) },
+		set: func(c *Config, v string) error { return setInt(&c.WatchIntervalMinutes, v) },
+	},
+	{
+		key: "watch_log", flag: "watch-log", env: "MTGDL_WATCH_LOG", usage: "arquivo de log do watch; vazio usa .watch.log na pasta de download",
+		get: func(c *Config) string { return c.WatchLog },
+		set: func(c *Config, v string) error { c.WatchLog = v; return nil },
+	},
+	{
+		key: "max_retries", flag: "retries", env: "MTGDL_RETRIES", usage: "retentativas após erro de rede, 429 ou 5xx (0-10)",
+		get: func(c *Config) string { return strconv.Itoa(c.MaxRetries) },
+		set: func(c *Config, v string) error { return setInt(&c.MaxRetries, v) },
+	},
+	{
+		key: "retry_delay_ms", flag: "retry-delay", env: "MTGDL_RETRY_DELAY", usage: "espera inicial entre retentativas em ms, dobrada a cada vez (100-60000)",
+		get: func(c *Config) string { return strconv.Itoa(c.RetryDelayMs) },
+		set: func(c *Config, v string) error { return setInt(&c.RetryDelayMs, v) },
+	},
+	{
+		key: "api_rate", flag: "api-rate", env: "MTGDL_API_RATE", usage: "requisições por segundo à API, 0 = sem limite (0-100)",
+		get: func(c *Config) string { return formatRate(c.APIRate) },
+		set: func(c *Config, v string) error { return setFloat(&c.APIRate, v) },
+	},
+	{
+		key: "image_rate", flag: "image-rate", env: "MTGDL_IMAGE_RATE", usage: "downloads de imagem por segundo, 0 = sem limite (0-100)",
+		get: func(c *Config) string { return formatRate(c.ImageRate) },
+		set: func(c *Config, v string) error { return setFloat(&c.ImageRate, v) },
+	},
+}
+
+func findConfigField(key string) *configField {
+	for i := range configFields {
+		if configFields[i].key == key {
+			return &configFields[i]
+		}
+	}
+	return nil
+}
+
+func setInt(target *int, value string) error {
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return fmt.Errorf("valor numérico inválido %q", value)
+	}
+	*target = n
+	return nil
+}
+
+func setBool(target *bool, value string) error {
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return fmt.Errorf("valor booleano inválido %q (use true ou false)", value)
+	}
+	*target = b
+	return nil
+}
+
+func setFloat(target *float64, value string) error {
+	n, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return fmt.Errorf("valor numérico inválido %q", value)
+	}
+	*target = n
+	return nil
+}
+
+func formatRate(rate float64) string {
+	return strconv.FormatFloat(rate, 'f', -1, 64)
+}
+
+// defaultConfigPath retorna o caminho do arquivo de configuração na pasta de configuração do usuário
+func defaultConfigPath() string {
+	if path := os.Getenv("MTGDL_CONFIG"); path != "" {
+		return path
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "mtg-card-downloader.json"
+	}
+	return filepath.Join(dir, "mtg-card-downloader", "config.json")
+}
+
+// defaultCachePath é um caminho dentro da pasta de cache do usuário
+func defaultCachePath(name string) string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return filepath.Join(".mtg-card-downloader", name)
+	}
+	return filepath.Join(dir, "mtg-card-downloader", name)
+}
+
+// loadConfig lê o arquivo de configuração sobre os valores padrão. Um arquivo inexistente não é erro.
+func loadConfig(path string) (Config, error) {
+	cfg := defaultConfig()
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return cfg, nil
+	}
+	if err != nil {
+		return cfg, fmt.Errorf("erro ao ler configuração %s: %w", path, err)
+	}
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return cfg, fmt.Errorf("erro ao decodificar configuração %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+func saveConfig(path string, cfg Config) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("erro ao criar diretório %s: %w", filepath.Dir(path), err)
+	}
+	data, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("erro ao salvar configuração %s: %w", path, err)
+	}
+	return os.Rename(tmpPath, path)
+}
+
+// persistSetting grava uma única opção no arquivo, preservando as demais como estão no disco
+// (valores vindos de flags ou do ambiente não são gravados)
+func persistSetting(path, key, value string) error {
+	field := findConfigField(key)
+	if field == nil {
+		return fmt.Errorf("opção desconhecida %q", key)
+	}
+	cfg, err := loadConfig(path)
+	if err != nil {
+		return err
+	}
+	if err := field.set(&cfg, value); err != nil {
+		return err
+	}
+	if err := cfg.validate(); err != nil {
+		return err
+	}
+	return saveConfig(path, cfg)
+}
+
+// applyEnv sobrescreve a configuração com as variáveis de ambiente definidas
+func (c *Config) applyEnv() error {
+	for _, field := range configFields {
+		if v := os.Getenv(field.env); v != "" {
+			if err := field.set(c, v); err != nil {
+				return fmt.Errorf("%s: %w", field.env, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (c Config) validate() error {
+	if c.DownloadDir == "" {
+		return fmt.Errorf("pasta de download não pode ser vazia")
+	}
+	if err := validateImageTypes(c.Quality); err != nil {
+		return err
+	}
+	if c.MaxWorkers < 1 || c.MaxWorkers > 50 {
+		return fmt.Errorf("número de workers deve ser entre 1 e 50")
+	}
+	if c.MaxRetries < 0 || c.MaxRetries > 10 {
+		return fmt.Errorf("número de retentativas deve ser entre 0 e 10")
+	}
+	if c.RetryDelayMs < 100 || c.RetryDelayMs > 60000 {
+		return fmt.Errorf("espera inicial deve ser entre 100 e 60000 ms")
+	}
+	if c.APIRate < 0 || c.APIRate > 100 || c.ImageRate < 0 || c.ImageRate > 100 {
+		return fmt.Errorf("limites de requisições devem ser entre 0 (sem limite) e 100 por segundo")
+	}
+	if err := validateNameTemplate(c.NameTemplate); err != nil {
+		return err
+	}
+	if !containsString(variantOptions, c.Variants) {
+		return fmt.Errorf("opção de variantes inválida %q (use all ou first)", c.Variants)
+	}
+	if !containsString(faceNameOptions, c.FaceNames) {
+		return fmt.Errorf("opção de faces inválida %q (use face ou paired)", c.FaceNames)
+	}
+	if u, err := url.Parse(c.CardBackURL); c.CardBack && (err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "") {
+		return fmt.Errorf("endereço do verso padrão inválido %q", c.CardBackURL)
+	}
+	if _, err := parseSetFilter(c.AllFilter); err != nil {
+		return err
+	}
+	if c.RelatedFolder != "" {
+		if err := validateNameTemplate(c.RelatedFolder); err != nil || strings.Contains(c.RelatedFolder, "{") {
+			return fmt.Errorf("subpasta de tokens inválida %q", c.RelatedFolder)
+		}
+	}
+	if c.UseCatalog && c.CatalogDir == "" {
+		return fmt.Errorf("pasta do catálogo não pode ser vazia com use_catalog ligado")
+	}
+	if c.Cache && c.CacheDir == "" {
+		return fmt.Errorf("pasta do cache não pode ser vazia com o cache ligado")
+	}
+	if c.CacheTTLMinutes < 0 {
+		return fmt.Errorf("validade do cache não pode ser negativa")
+	}
+	if c.Offline && !c.Cache && !c.UseCatalog {
+		return fmt.Errorf("modo offline precisa do cache ou do catálogo ligado")
+	}
+	if c.WatchIntervalMinutes < 1 || c.WatchIntervalMinutes > 10080 {
+		return fmt.Errorf("intervalo do watch deve ser entre 1 e 10080 minutos")
+	}
+	if !containsString(languageOptions, c.Lang) {
+		return fmt.Errorf("idioma inválido %q (use %s)", c.Lang, strings.Join(languageOptions, ", "))
+	}
+	if c.Preset != "" && findExportPreset(c.Preset) == nil {
+		return fmt.Errorf("preset desconhecido %q (use %s)", c.Preset, strings.Join(exportPresetNames(), ", "))
+	}
+	return validateAPIBase(c.APIBase)
+}
+
+// parseImageTypes separa a lista de tipos de imagem, ignorando espaços e entradas vazias
+func parseImageTypes(value string) []string {
+	var types []string
+	for _, imageType := range strings.Split(value, ",") {
+		if imageType = strings.ToLower(strings.TrimSpace(imageType)); imageType != "" {
+			types = append(types, imageType)
+		}
+	}
+	return types
+}
+
+func validateImageTypes(value string) error {
+	types := parseImageTypes(value)
+	if len(types) == 0 {
+		return fmt.Errorf("informe ao menos um tipo de imagem (%s)", strings.Join(qualityOptions, ", "))
+	}
+	seen := make(map[string]bool)
+	for _, imageType := range types {
+		if !containsString(qualityOptions, imageType) {
+			return fmt.Errorf("tipo de imagem inválido %q (use %s)", imageType, strings.Join(qualityOptions, ", "))
+		}
+		if seen[imageType] {
+			return fmt.Errorf("tipo de imagem repetido %q", imageType)
+		}
+		seen[imageType] = true
+	}
+	return nil
+}
+
+func validateAPIBase(base string) error {
+	u, err := url.Parse(base)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("endereço da API inválido %q (ex: %s)", base, defaultAPIBase)
+	}
+	return nil
+}
+
+func containsString(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
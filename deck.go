@@ -0,0 +1,515 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// DeckEntry é uma linha de uma lista de deck
+type DeckEntry struct {
+	Count           int
+	Name            string
+	SetCode         string // opcional, ex: "lea"
+	CollectorNumber string // opcional, só faz sentido junto com SetCode
+	Section         string // main, sideboard, commander...
+}
+
+// deckParseResult é o resultado da leitura de um arquivo de deck
+type deckParseResult struct {
+	Source   string // caminho do arquivo lido
+	Format   string
+	Entries  []DeckEntry
+	Unparsed []string // linhas que não puderam ser interpretadas
+}
+
+// deckFormat interpreta um formato de exportação de deck
+type deckFormat interface {
+	Name() string
+	Detect(fileName string, data []byte) bool
+	Parse(data []byte) (deckParseResult, error)
+}
+
+// Formatos na ordem em que a detecção é tentada; o texto simples aceita qualquer coisa
+var deckFormats = []deckFormat{
+	mtgoDeckFormat{},
+	csvDeckFormat{},
+	arenaDeckFormat{},
+	textDeckFormat{},
+}
+
+func findDeckFormat(name string) deckFormat {
+	for _, format := range deckFormats {
+		if format.Name() == name {
+			return format
+		}
+	}
+	return nil
+}
+
+func deckFormatNames() []string {
+	var names []string
+	for _, format := range deckFormats {
+		names = append(names, format.Name())
+	}
+	return names
+}
+
+// readDecklist lê e interpreta um arquivo de deck ("-" lê da entrada padrão). Com formatName
+// vazio o formato é detectado automaticamente.
+func readDecklist(path, formatName string) (deckParseResult, error) {
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return deckParseResult{}, fmt.Errorf("erro ao abrir decklist %s: %w", path, err)
+	}
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")) // BOM gerado por alguns sites
+
+	var format deckFormat
+	if formatName != "" {
+		if format = findDeckFormat(formatName); format == nil {
+			return deckParseResult{}, fmt.Errorf("formato de deck desconhecido %q (use %s)", formatName, strings.Join(deckFormatNames(), ", "))
+		}
+	} else {
+		for _, candidate := range deckFormats {
+			if candidate.Detect(path, data) {
+				format = candidate
+				break
+			}
+		}
+	}
+
+	result, err := format.Parse(data)
+	if err != nil {
+		return result, fmt.Errorf("erro ao ler decklist (%s): %w", format.Name(), err)
+	}
+	result.Source = path
+	result.Format = format.Name()
+	return result, nil
+}
+
+// Ex: "4 Lightning Bolt", "1x Black Lotus (LEA) 232", "Opt"
+var deckLineRe = regexp.MustCompile(`^(?:(\d+)x?\s+)?(.+?)(?:\s+\(([A-Za-z0-9]+)\)(?:\s+(\S+))?)?$`)
+
+// Marcações de acabamento no fim da linha, ex: "*F*" do Moxfield
+var deckFinishRe = regexp.MustCompile(`\s+\*[A-Za-z]+\*$`)
+
+// Cabeçalhos de seção reconhecidos (comparados sem ":" e em minúsculas)
+var deckSections = map[string]string{
+	"deck":       "main",
+	"main":       "main",
+	"mainboard":  "main",
+	"sideboard":  "sideboard",
+	"commander":  "commander",
+	"companion":  "companion",
+	"maybeboard": "maybeboard",
+}
+
+// csvSections reconhece a seção dentro de colunas livres do CSV ("Commander Deck", "Sideboard,Lands").
+// A ordem importa: as palavras mais específicas vêm antes de "main" e "deck", que aparecem dentro
+// dos nomes de outras seções.
+var csvSections = []struct{ key, section string }{
+	{"commander", "commander"},
+	{"companion", "companion"},
+	{"maybeboard", "maybeboard"},
+	{"sideboard", "sideboard"},
+	{"mainboard", "main"},
+	{"main", "main"},
+	{"deck", "main"},
+}
+
+// textDeckFormat é a lista em texto simples, uma carta por linha. Linhas vazias depois de
+// cartas da seção principal iniciam o sideboard, como nos exports mais comuns.
+type textDeckFormat struct{}
+
+func (textDeckFormat) Name() string { return "text" }
+
+func (textDeckFormat) Detect(fileName string, data []byte) bool { return true }
+
+func (textDeckFormat) Parse(data []byte) (deckParseResult, error) {
+	return parseDeckText(data, nil)
+}
+
+// arenaDeckFormat é o export do MTG Arena: "4 Opt (XLN) 65", com cabeçalhos Deck/Sideboard
+// e um bloco opcional "About" com o nome do deck
+type arenaDeckFormat struct{}
+
+// Códigos usados pelo Arena que diferem do Scryfall
+var arenaSetCodes = map[string]string{
+	"dar": "dom",
+}
+
+var arenaLineRe = regexp.MustCompile(`^\d+\s+.+\s+\([A-Za-z0-9]+\)\s+\S+$`)
+
+func (arenaDeckFormat) Name() string { return "arena" }
+
+func (arenaDeckFormat) Detect(fileName string, data []byte) bool {
+	lines, matches := 0, 0
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || deckSections[strings.ToLower(line)] != "" || line == "About" || strings.HasPrefix(line, "Name ") {
+			continue
+		}
+		lines++
+		if arenaLineRe.MatchString(line) {
+			matches++
+		}
+	}
+	return lines > 0 && matches*2 > lines
+}
+
+func (arenaDeckFormat) Parse(data []byte) (deckParseResult, error) {
+	inAbout := false
+	return parseDeckText(data, func(line string) bool {
+		switch {
+		case line == "About":
+			inAbout = true
+		case inAbout && strings.HasPrefix(line, "Name "):
+		default:
+			inAbout = false
+			return false
+		}
+		return true
+	})
+}
+
+// parseDeckText interpreta listas em texto. skip permite que um formato ignore linhas próprias.
+func parseDeckText(data []byte, skip func(line string) bool) (deckParseResult, error) {
+	var result deckParseResult
+	section := "main"
+	seenCards := false
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			if seenCards && section == "main" {
+				section = "sideboard"
+			}
+			continue
+		}
+		if strings.HasPrefix(line, "#") || strings.HasPrefix(line, "//") || (skip != nil && skip(line)) {
+			continue
+		}
+		if name, ok := deckSections[strings.ToLower(strings.TrimSuffix(line, ":"))]; ok {
+			section = name
+			seenCards = false
+			continue
+		}
+
+		lineSection := section
+		cardLine := line
+		if strings.HasPrefix(strings.ToUpper(cardLine), "SB:") {
+			lineSection = "sideboard"
+			cardLine = strings.TrimSpace(cardLine[3:])
+		}
+
+		entry, ok := parseDeckLine(deckFinishRe.ReplaceAllString(cardLine, ""))
+		if !ok {
+			result.Unparsed = append(result.Unparsed, line)
+			continue
+		}
+		if code, ok := arenaSetCodes[entry.SetCode]; ok {
+			entry.SetCode = code
+		}
+		entry.Section = lineSection
+		result.Entries = append(result.Entries, entry)
+		seenCards = true
+	}
+	if err := scanner.Err(); err != nil {
+		return result, err
+	}
+	return result, nil
+}
+
+func parseDeckLine(line string) (DeckEntry, bool) {
+	match := deckLineRe.FindStringSubmatch(line)
+	if match == nil {
+		return DeckEntry{}, false
+	}
+
+	count := 1
+	if match[1] != "" {
+		count, _ = strconv.Atoi(match[1])
+	}
+	name := strings.TrimSpace(match[2])
+	if strings.IndexFunc(name, unicode.IsLetter) < 0 || count <= 0 {
+		return DeckEntry{}, false
+	}
+
+	return DeckEntry{
+		Count:           count,
+		Name:            name,
+		SetCode:         strings.ToLower(match[3]),
+		CollectorNumber: match[4],
+	}, true
+}
+
+// mtgoDeckFormat é o arquivo .dek (XML) exportado pelo Magic Online
+type mtgoDeckFormat struct{}
+
+func (mtgoDeckFormat) Name() string { return "mtgo" }
+
+func (mtgoDeckFormat) Detect(fileName string, data []byte) bool {
+	return strings.EqualFold(filepath.Ext(fileName), ".dek") || bytes.Contains(data, []byte("<Deck"))
+}
+
+func (mtgoDeckFormat) Parse(data []byte) (deckParseResult, error) {
+	var deck struct {
+		Cards []struct {
+			Quantity  string `xml:"Quantity,attr"`
+			Sideboard string `xml:"Sideboard,attr"`
+			Name      string `xml:"Name,attr"`
+		} `xml:"Cards"`
+	}
+	if err := xml.Unmarshal(data, &deck); err != nil {
+		return deckParseResult{}, err
+	}
+
+	var result deckParseResult
+	for _, card := range deck.Cards {
+		count, err := strconv.Atoi(card.Quantity)
+		if err != nil || count <= 0 || strings.TrimSpace(card.Name) == "" {
+			result.Unparsed = append(result.Unparsed, fmt.Sprintf(`<Cards Quantity="%s" Name="%s">`, card.Quantity, card.Name))
+			continue
+		}
+		section := "main"
+		if strings.EqualFold(card.Sideboard, "true") {
+			section = "sideboard"
+		}
+		result.Entries = append(result.Entries, DeckEntry{Count: count, Name: strings.TrimSpace(card.Name), Section: section})
+	}
+	return result, nil
+}
+
+// csvDeckFormat cobre os exports CSV do Moxfield e do Archidekt, localizando as colunas pelo cabeçalho
+type csvDeckFormat struct{}
+
+// Nomes de coluna aceitos para cada campo (em minúsculas)
+var (
+	csvCountColumns   = []string{"count", "quantity", "qty"}
+	csvNameColumns    = []string{"name", "card name", "card"}
+	csvSetColumns     = []string{"edition code", "set code", "edition", "set"}
+	csvNumberColumns  = []string{"collector number", "collector_number", "number"}
+	csvSectionColumns = []string{"board", "section", "categories", "category"}
+)
+
+func (csvDeckFormat) Name() string { return "csv" }
+
+func (csvDeckFormat) Detect(fileName string, data []byte) bool {
+	if strings.EqualFold(filepath.Ext(fileName), ".csv") {
+		return true
+	}
+	header, err := csv.NewReader(bytes.NewReader(data)).Read()
+	return err == nil && len(header) > 1 && csvColumn(header, csvNameColumns) >= 0
+}
+
+func (csvDeckFormat) Parse(data []byte) (deckParseResult, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		return deckParseResult{}, err
+	}
+	if len(records) == 0 {
+		return deckParseResult{}, nil
+	}
+
+	header := records[0]
+	nameCol := csvColumn(header, csvNameColumns)
+	if nameCol < 0 {
+		return deckParseResult{}, fmt.Errorf("coluna com o nome da carta não encontrada")
+	}
+	countCol := csvColumn(header, csvCountColumns)
+	setCol := csvColumn(header, csvSetColumns)
+	numberCol := csvColumn(header, csvNumberColumns)
+	sectionCol := csvColumn(header, csvSectionColumns)
+
+	field := func(record []string, col int) string {
+		if col < 0 || col >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[col])
+	}
+
+	var result deckParseResult
+	for _, record := range records[1:] {
+		name := field(record, nameCol)
+		count := 1
+		if raw := field(record, countCol); raw != "" {
+			count, err = strconv.Atoi(raw)
+			if err != nil {
+				count = 0
+			}
+		}
+		if name == "" || count <= 0 {
+			result.Unparsed = append(result.Unparsed, strings.Join(record, ","))
+			continue
+		}
+
+		section := "main"
+		if raw := strings.ToLower(field(record, sectionCol)); raw != "" {
+			for _, candidate := range csvSections {
+				if strings.Contains(raw, candidate.key) {
+					section = candidate.section
+					break
+				}
+			}
+		}
+
+		entry := DeckEntry{
+			Count:           count,
+			Name:            name,
+			SetCode:         strings.ToLower(field(record, setCol)),
+			CollectorNumber: field(record, numberCol),
+			Section:         section,
+		}
+		// Nomes de edição por extenso não servem como código de set
+		if strings.Contains(entry.SetCode, " ") {
+			entry.SetCode, entry.CollectorNumber = "", ""
+		}
+		result.Entries = append(result.Entries, entry)
+	}
+	return result, nil
+}
+
+func csvColumn(header []string, names []string) int {
+	for _, name := range names {
+		for i, column := range header {
+			if strings.EqualFold(strings.TrimSpace(column), name) {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// resolveDeckEntry encontra a impressão exata de uma linha do deck: set e número quando
+// informados, senão a carta pelo nome no set ou a impressão padrão. Só um 404 passa para a
+// busca seguinte, com aviso no log; qualquer outro erro (rede, 429 depois das retentativas)
+// faz a linha falhar em vez de trocar a impressão pedida por outra.
+func (d *Downloader) resolveDeckEntry(ctx context.Context, entry DeckEntry) (*Card, error) {
+	requested := entry.Name
+	if entry.SetCode != "" {
+		requested += " (" + strings.ToUpper(entry.SetCode) + ")"
+		if entry.CollectorNumber != "" {
+			requested += " " + entry.CollectorNumber
+		}
+	}
+	fallback := func(card *Card, err error) (*Card, error) {
+		if err == nil {
+			d.logf("%s não encontrado, usando impressão %s (%s) %s", requested, card.Name, strings.ToUpper(card.Set), card.CollectorNumber)
+		}
+		return card, err
+	}
+
+	if entry.SetCode != "" && entry.CollectorNumber != "" {
+		card, err := d.fetchCardURL(ctx, d.apiURL(fmt.Sprintf("/cards/%s/%s", url.PathEscape(entry.SetCode), url.PathEscape(entry.CollectorNumber))))
+		if !errors.Is(err, errCardNotFound) {
+			return card, err
+		}
+	}
+
+	query := url.Values{"exact": {entry.Name}}
+	if entry.SetCode != "" {
+		query.Set("set", entry.SetCode)
+		card, err := d.fetchCardURL(ctx, d.apiURL("/cards/named?"+query.Encode()))
+		if !errors.Is(err, errCardNotFound) {
+			if entry.CollectorNumber == "" {
+				return card, err
+			}
+			return fallback(card, err)
+		}
+		query.Del("set")
+	}
+
+	card, err := d.fetchCardURL(ctx, d.apiURL("/cards/named?"+query.Encode()))
+	if !errors.Is(err, errCardNotFound) {
+		if entry.SetCode == "" {
+			return card, err
+		}
+		return fallback(card, err)
+	}
+	return fallback(d.fetchCard(ctx, entry.Name))
+}
+
+// downloadDeck resolve cada carta do deck e baixa exatamente essas impressões
+func (d *Downloader) downloadDeck(ctx context.Context, deck deckParseResult) downloadCompleteMsg {
+	var completed, failed []string
+	var allTasks []imageTask
+	seen := make(map[string]bool)
+	planner := d.newPathPlanner()
+	entries := deck.Entries
+	resolved := len(entries)
+
+	for i, entry := range entries {
+		if ctx.Err() != nil {
+			resolved = i
+			break
+		}
+		card, err := d.resolveDeckEntry(ctx, entry)
+		if err == nil {
+			card, err = d.localizeCard(ctx, card)
+		}
+		if ctx.Err() != nil {
+			resolved = i
+			break
+		}
+		if err != nil {
+			d.logf("[%d/%d] %s: %v", i+1, len(entries), entry.Name, err)
+			failed = append(failed, entry.Name)
+			continue
+		}
+		d.logf("[%d/%d] %s (%s) %s", i+1, len(entries), card.Name, strings.ToUpper(card.Set), card.CollectorNumber)
+		completed = append(completed, card.Name)
+
+		// A mesma impressão pode aparecer no main e no sideboard
+		if seen[card.ID] {
+			continue
+		}
+		seen[card.ID] = true
+		allTasks = append(allTasks, d.planCards(planner, []Card{*card})...)
+	}
+	// Cartas não resolvidas antes do cancelamento não têm tasks nem entram no journal; aparecem
+	// como falha para o resumo mostrar o que ficou de fora
+	if resolved < len(entries) {
+		d.logf("Cancelado antes de resolver %d cartas", len(entries)-resolved)
+		for _, entry := range entries[resolved:] {
+			failed = append(failed, entry.Name)
+		}
+	}
+
+	job := d.createJob("deck "+deck.Source, nil)
+	defer d.closeJob(job)
+	allTasks = job.plan(allTasks)
+
+	successCount := d.runTasks(ctx, allTasks, job)
+
+	return downloadCompleteMsg{
+		success:           successCount > 0,
+		message:           fmt.Sprintf("Decklist (%s) finalizada: %d cartas, %d/%d imagens baixadas", deck.Format, len(completed), successCount, len(allTasks)),
+		completed:         completed,
+		failed:            failed,
+		skipped:           deck.Unparsed,
+		cancelled:         ctx.Err() != nil,
+		variantsKept:      planner.kept,
+		variantsCollapsed: planner.collapsed,
+	}
+}
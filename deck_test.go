@@ -0,0 +1,130 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestReadDecklist(t *testing.T) {
+	tests := []struct {
+		name         string
+		file         string
+		data         string
+		wantFormat   string
+		wantEntries  []DeckEntry
+		wantUnparsed []string
+	}{
+		{
+			name:       "texto com seções, SB:, acabamento e sideboard por linha vazia",
+			file:       "burn.txt",
+			data:       "Commander:\n1 Krenko, Mob Boss\n\nDeck\n4 Lightning Bolt\n1x Black Lotus (LEA) 232\n2 Opt *F*\nSB: 1 Negate\n1234\n0 Shock\n\n2 Duress\n",
+			wantFormat: "text",
+			wantEntries: []DeckEntry{
+				{Count: 1, Name: "Krenko, Mob Boss", Section: "commander"},
+				{Count: 4, Name: "Lightning Bolt", Section: "main"},
+				{Count: 1, Name: "Black Lotus", SetCode: "lea", CollectorNumber: "232", Section: "main"},
+				{Count: 2, Name: "Opt", Section: "main"},
+				{Count: 1, Name: "Negate", Section: "sideboard"},
+				{Count: 2, Name: "Duress", Section: "sideboard"},
+			},
+			wantUnparsed: []string{"1234", "0 Shock"},
+		},
+		{
+			name:       "arena com bloco About e código de set traduzido",
+			file:       "arena.txt",
+			data:       "About\nName Mono Blue\n\nDeck\n4 Opt (XLN) 65\n2 Island (DAR) 250\n\nSideboard\n1 Negate (RIX) 44\n",
+			wantFormat: "arena",
+			wantEntries: []DeckEntry{
+				{Count: 4, Name: "Opt", SetCode: "xln", CollectorNumber: "65", Section: "main"},
+				{Count: 2, Name: "Island", SetCode: "dom", CollectorNumber: "250", Section: "main"},
+				{Count: 1, Name: "Negate", SetCode: "rix", CollectorNumber: "44", Section: "sideboard"},
+			},
+		},
+		{
+			name: "mtgo .dek",
+			file: "deck.dek",
+			data: `<?xml version="1.0" encoding="utf-8"?>
+<Deck xmlns:xsd="http://www.w3.org/2001/XMLSchema">
+  <Cards CatID="1" Quantity="4" Sideboard="false" Name="Lightning Bolt" />
+  <Cards CatID="2" Quantity="2" Sideboard="true" Name="Negate" />
+  <Cards CatID="3" Quantity="x" Sideboard="false" Name="Opt" />
+</Deck>`,
+			wantFormat: "mtgo",
+			wantEntries: []DeckEntry{
+				{Count: 4, Name: "Lightning Bolt", Section: "main"},
+				{Count: 2, Name: "Negate", Section: "sideboard"},
+			},
+			wantUnparsed: []string{`<Cards Quantity="x" Name="Opt">`},
+		},
+		{
+			name:       "csv do moxfield detectado pelo cabeçalho",
+			file:       "moxfield.txt",
+			data:       "Count,Name,Edition,Foil,Collector Number\n4,Lightning Bolt,m21,,199\n1,Opt,Throne of Eldraine,,59\n,,,,\n",
+			wantFormat: "csv",
+			wantEntries: []DeckEntry{
+				{Count: 4, Name: "Lightning Bolt", SetCode: "m21", CollectorNumber: "199", Section: "main"},
+				{Count: 1, Name: "Opt", Section: "main"},
+			},
+			wantUnparsed: []string{",,,,"},
+		},
+		{
+			name:       "csv do archidekt com categorias",
+			file:       "archidekt.csv",
+			data:       "Quantity,Name,Categories,Edition Code,Collector Number\n1,Atraxa,Commander Deck,one,1\n2,Sol Ring,\"Ramp,Artifact\",c21,263\n1,Negate,Sideboard,rix,44\nabc,Opt,,,\n",
+			wantFormat: "csv",
+			wantEntries: []DeckEntry{
+				{Count: 1, Name: "Atraxa", SetCode: "one", CollectorNumber: "1", Section: "commander"},
+				{Count: 2, Name: "Sol Ring", SetCode: "c21", CollectorNumber: "263", Section: "main"},
+				{Count: 1, Name: "Negate", SetCode: "rix", CollectorNumber: "44", Section: "sideboard"},
+			},
+			wantUnparsed: []string{"abc,Opt,,,"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tt.file)
+			if err := os.WriteFile(path, []byte(tt.data), 0644); err != nil {
+				t.Fatal(err)
+			}
+			got, err := readDecklist(path, "")
+			if err != nil {
+				t.Fatalf("readDecklist: %v", err)
+			}
+			if got.Format != tt.wantFormat {
+				t.Errorf("formato = %q, esperado %q", got.Format, tt.wantFormat)
+			}
+			if !reflect.DeepEqual(got.Entries, tt.wantEntries) {
+				t.Errorf("entradas:\n got %+v\nwant %+v", got.Entries, tt.wantEntries)
+			}
+			if !reflect.DeepEqual(got.Unparsed, tt.wantUnparsed) {
+				t.Errorf("não interpretadas = %q, esperado %q", got.Unparsed, tt.wantUnparsed)
+			}
+		})
+	}
+}
+
+func TestParseDeckLine(t *testing.T) {
+	tests := []struct {
+		line string
+		want DeckEntry
+		ok   bool
+	}{
+		{"4 Lightning Bolt", DeckEntry{Count: 4, Name: "Lightning Bolt"}, true},
+		{"Opt", DeckEntry{Count: 1, Name: "Opt"}, true},
+		{"1x Black Lotus (LEA) 232", DeckEntry{Count: 1, Name: "Black Lotus", SetCode: "lea", CollectorNumber: "232"}, true},
+		{"2 Fire // Ice (MH2) 290", DeckEntry{Count: 2, Name: "Fire // Ice", SetCode: "mh2", CollectorNumber: "290"}, true},
+		{"1 Borrowing 100,000 Arrows", DeckEntry{Count: 1, Name: "Borrowing 100,000 Arrows"}, true},
+		{"3 Plains (M21)", DeckEntry{Count: 3, Name: "Plains", SetCode: "m21"}, true},
+		{"0 Opt", DeckEntry{}, false},
+		{"12", DeckEntry{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := parseDeckLine(tt.line)
+		if ok != tt.ok || got != tt.want {
+			t.Errorf("parseDeckLine(%q) = %+v, %v; esperado %+v, %v", tt.line, got, ok, tt.want, tt.ok)
+		}
+	}
+}
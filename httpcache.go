@@ -0,0 +1,200 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// As respostas da API (lista de sets, páginas de cartas, cartas) ficam guardadas em disco.
+// Dentro do TTL são usadas sem consultar o Scryfall; depois disso a requisição leva o ETag e o
+// Last-Modified guardados, e um 304 renova a entrada sem baixar tudo de novo. As imagens não
+// passam pelo cache: os próprios arquivos baixados cumprem esse papel. As sugestões de nome também
+// não: são pedidas a cada tecla digitada e encheriam a pasta de consultas que não se repetem.
+
+// uncachedPaths são endpoints da API cujas respostas nunca vão para o cache
+var uncachedPaths = []string{"/cards/autocomplete"}
+
+// errOffline é devolvido quando o modo offline impediria uma requisição
+var errOffline = errors.New("modo offline: requisição não permitida")
+
+type cacheEntry struct {
+	URL          string    `json:"url"`
+	ETag         string    `json:"etag,omitempty"`
+	LastModified string    `json:"last_modified,omitempty"`
+	StoredAt     time.Time `json:"stored_at"`
+	Body         []byte    `json:"body"`
+}
+
+// httpCache é uma pasta com uma entrada JSON por URL
+type httpCache struct {
+	dir string
+	ttl time.Duration
+}
+
+func (c *httpCache) path(url string) string {
+	sum := sha256.Sum256([]byte(url))
+	return filepath.Join(c.dir, hex.EncodeToString(sum[:])+".json")
+}
+
+func (c *httpCache) load(url string) *cacheEntry {
+	data, err := os.ReadFile(c.path(url))
+	if err != nil {
+		return nil
+	}
+	var entry cacheEntry
+	if json.Unmarshal(data, &entry) != nil || entry.URL != url {
+		return nil // Entrada corrompida ou colisão: trata como ausente
+	}
+	return &entry
+}
+
+// store grava a entrada num arquivo temporário e renomeia, para leituras concorrentes nunca
+// verem um JSON pela metade
+func (c *httpCache) store(entry *cacheEntry) error {
+	if err := os.MkdirAll(c.dir, 0755); err != nil {
+		return err
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(c.dir, "*.tmp")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), c.path(entry.URL))
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
+}
+
+func (c *httpCache) fresh(entry *cacheEntry) bool {
+	return time.Since(entry.StoredAt) < c.ttl
+}
+
+// entries lê todas as entradas, ordenadas por URL
+func (c *httpCache) entries() ([]cacheEntry, int64, error) {
+	files, err := os.ReadDir(c.dir)
+	if os.IsNotExist(err) {
+		return nil, 0, nil
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	var entries []cacheEntry
+	var size int64
+	for _, file := range files {
+		if !strings.HasSuffix(file.Name(), ".json") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(c.dir, file.Name()))
+		if err != nil {
+			continue
+		}
+		var entry cacheEntry
+		if json.Unmarshal(data, &entry) == nil {
+			entries = append(entries, entry)
+			size += int64(len(data))
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].URL < entries[j].URL })
+	return entries, size, nil
+}
+
+func (c *httpCache) clear() error {
+	return os.RemoveAll(c.dir)
+}
+
+// cacheableURL indica se a resposta de url pode ir para o cache
+func cacheableURL(url string) bool {
+	for _, path := range uncachedPaths {
+		if strings.Contains(url, path+"?") {
+			return false
+		}
+	}
+	return true
+}
+
+// cachedResponse devolve o corpo guardado como se fosse uma resposta 200 da API
+func cachedResponse(entry *cacheEntry) *http.Response {
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{},
+		Body:       io.NopCloser(bytes.NewReader(entry.Body)),
+	}
+}
+
+// getAPI faz um GET na API passando pelo cache. Só respostas 200 são guardadas; as demais
+// (404 de busca vazia, erros) seguem direto para quem chamou.
+func (d *Downloader) getAPI(ctx context.Context, url string) (*http.Response, error) {
+	if d.cache == nil || !cacheableURL(url) {
+		return d.get(ctx, d.apiLimiter, url)
+	}
+
+	entry := d.cache.load(url)
+	if entry != nil && (d.offline || d.cache.fresh(entry)) {
+		return cachedResponse(entry), nil
+	}
+	if d.offline {
+		return nil, fmt.Errorf("%w: %s não está no cache", errOffline, url)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if entry != nil {
+		if entry.ETag != "" {
+			req.Header.Set("If-None-Match", entry.ETag)
+		}
+		if entry.LastModified != "" {
+			req.Header.Set("If-Modified-Since", entry.LastModified)
+		}
+	}
+	resp, err := d.do(ctx, d.apiLimiter, req)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusNotModified && entry != nil:
+		resp.Body.Close()
+		entry.StoredAt = time.Now()
+		if err := d.cache.store(entry); err != nil {
+			d.logf("Falha ao atualizar o cache: %v", err)
+		}
+		return cachedResponse(entry), nil
+
+	case resp.StatusCode == http.StatusOK:
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		entry = &cacheEntry{URL: url, ETag: resp.Header.Get("ETag"), LastModified: resp.Header.Get("Last-Modified"), StoredAt: time.Now(), Body: body}
+		if err := d.cache.store(entry); err != nil {
+			d.logf("Falha ao gravar o cache: %v", err)
+		}
+		return cachedResponse(entry), nil
+	}
+	return resp, nil
+}
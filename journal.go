@@ -0,0 +1,439 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// O journal registra em disco cada job de download para que execuções interrompidas possam
+// ser retomadas. Cada job é um arquivo JSON Lines em <downloadDir>/.jobs com um cabeçalho, as
+// tasks planejadas (set a set) e o resultado de cada task. Quando um job termina sem pendências
+// o arquivo é removido, então todo arquivo presente representa um job inacabado. Uma task que
+// falha maxTaskFailures vezes, somando todas as execuções, é abandonada e deixa de contar como
+// pendência, para que uma imagem que nunca baixa não prenda o job para sempre.
+
+const jobsDirName = ".jobs"
+
+const maxTaskFailures = 3
+
+// journalRecord é uma linha do arquivo do job
+type journalRecord struct {
+	Type string `json:"type"` // job, task, set, done, failed
+
+	// job
+	ID          string   `json:"id,omitempty"`
+	Created     string   `json:"created,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Sets        []string `json:"sets,omitempty"`
+
+	// task
+	Task *imageTask `json:"task,omitempty"`
+
+	// set: paginação concluída e tasks gravadas
+	Code string `json:"code,omitempty"`
+
+	// done, failed
+	TaskID int    `json:"task_id,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+type jobJournal struct {
+	ID          string
+	Created     time.Time
+	Description string
+	Sets        []string // sets pedidos (vazio em jobs de carta ou deck)
+
+	path     string
+	mu       sync.Mutex
+	file     *os.File
+	err      error // primeiro erro de escrita
+	tasks    []imageTask
+	planned  map[string]bool
+	done     map[int]bool
+	failures map[int]int    // falhas registradas por task
+	byPath   map[string]int // task já planejada para cada URL e caminho
+}
+
+func jobsDir(downloadDir string) string {
+	return filepath.Join(downloadDir, jobsDirName)
+}
+
+// createJob inicia um journal novo. Falhas são registradas no log e o download segue sem journal.
+func (d *Downloader) createJob(description string, sets []string) *jobJournal {
+	job, err := newJobJournal(jobsDir(d.downloadDir), description, sets)
+	if err != nil {
+		d.logf("Journal desativado: %v", err)
+		return nil
+	}
+	return job
+}
+
+// closeJob fecha o journal e avisa quando o job ficou com pendências
+func (d *Downloader) closeJob(job *jobJournal) {
+	if job == nil {
+		return
+	}
+	pending, unplanned := len(job.pendingTasks()), len(job.unplannedSets())
+	if abandoned := len(job.abandonedTasks()); abandoned > 0 {
+		d.logf("Job %s: %d imagens abandonadas após %d falhas", job.ID, abandoned, maxTaskFailures)
+	}
+	if err := job.close(); err != nil {
+		d.logf("Erro no journal do job %s: %v", job.ID, err)
+	}
+	if pending > 0 || unplanned > 0 {
+		d.logf("Job %s ficou com %d imagens e %d sets pendentes (use resume %s)", job.ID, pending, unplanned, job.ID)
+	}
+}
+
+func newJobJournal(dir, description string, sets []string) (*jobJournal, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("erro ao criar diretório %s: %w", dir, err)
+	}
+
+	created := time.Now()
+	id := created.Format("20060102-150405")
+	path := filepath.Join(dir, id+".jsonl")
+	for i := 2; ; i++ {
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			break
+		}
+		id = fmt.Sprintf("%s-%d", created.Format("20060102-150405"), i)
+		path = filepath.Join(dir, id+".jsonl")
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao criar journal %s: %w", path, err)
+	}
+
+	job := &jobJournal{
+		ID:          id,
+		Created:     created,
+		Description: description,
+		Sets:        sets,
+		path:        path,
+		file:        file,
+		planned:     make(map[string]bool),
+		done:        make(map[int]bool),
+		failures:    make(map[int]int),
+		byPath:      make(map[string]int),
+	}
+	job.write(journalRecord{Type: "job", ID: id, Created: created.Format(time.RFC3339), Description: description, Sets: sets})
+	if job.err != nil {
+		file.Close()
+		os.Remove(path)
+		return nil, fmt.Errorf("erro ao gravar journal %s: %w", path, job.err)
+	}
+	return job, nil
+}
+
+// loadJob lê um journal do disco. O arquivo só é reaberto para escrita por openForAppend.
+func loadJob(path string) (*jobJournal, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao abrir journal %s: %w", path, err)
+	}
+	defer file.Close()
+
+	job := &jobJournal{
+		path:     path,
+		planned:  make(map[string]bool),
+		done:     make(map[int]bool),
+		failures: make(map[int]int),
+		byPath:   make(map[string]int),
+	}
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var record journalRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			continue // Última linha pode ter ficado incompleta se o programa foi fechado
+		}
+		switch record.Type {
+		case "job":
+			job.ID = record.ID
+			job.Description = record.Description
+			job.Sets = record.Sets
+			job.Created, _ = time.Parse(time.RFC3339, record.Created)
+		case "task":
+			if record.Task != nil {
+				job.tasks = append(job.tasks, *record.Task)
+				job.byPath[taskKey(*record.Task)] = record.Task.ID
+			}
+		case "set":
+			job.planned[record.Code] = true
+		case "done":
+			job.done[record.TaskID] = true
+		case "failed":
+			job.failures[record.TaskID]++
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("erro ao ler journal %s: %w", path, err)
+	}
+	if job.ID == "" {
+		return nil, fmt.Errorf("journal %s sem cabeçalho", path)
+	}
+	return job, nil
+}
+
+func (j *jobJournal) openForAppend() error {
+	file, err := os.OpenFile(j.path, os.O_APPEND|os.O_RDWR, 0644)
+	if err != nil {
+		return fmt.Errorf("erro ao abrir journal %s: %w", j.path, err)
+	}
+
+	// Termina a linha deixada pela metade quando o programa foi fechado durante uma escrita
+	if info, err := file.Stat(); err == nil && info.Size() > 0 {
+		last := make([]byte, 1)
+		if _, err := file.ReadAt(last, info.Size()-1); err == nil && last[0] != '\n' {
+			file.Write([]byte("\n"))
+		}
+	}
+	j.file = file
+	return nil
+}
+
+// listJobs retorna os jobs inacabados de uma pasta de download, do mais recente ao mais antigo
+func listJobs(downloadDir string) ([]*jobJournal, error) {
+	paths, err := filepath.Glob(filepath.Join(jobsDir(downloadDir), "*.jsonl"))
+	if err != nil {
+		return nil, err
+	}
+
+	var jobs []*jobJournal
+	for _, path := range paths {
+		job, err := loadJob(path)
+		if err != nil {
+			continue
+		}
+		jobs = append(jobs, job)
+	}
+	sort.Slice(jobs, func(a, b int) bool { return jobs[a].ID > jobs[b].ID })
+	return jobs, nil
+}
+
+// findJob localiza um job inacabado pelo ID; ID vazio retorna o mais recente
+func findJob(downloadDir, id string) (*jobJournal, error) {
+	jobs, err := listJobs(downloadDir)
+	if err != nil {
+		return nil, err
+	}
+	for _, job := range jobs {
+		if id == "" || job.ID == id {
+			return job, nil
+		}
+	}
+	if id == "" {
+		return nil, fmt.Errorf("nenhum job pendente em %s", downloadDir)
+	}
+	return nil, fmt.Errorf("job %s não encontrado", id)
+}
+
+func (j *jobJournal) write(record journalRecord) {
+	data, err := json.Marshal(record)
+	if err == nil {
+		_, err = j.file.Write(append(data, '\n'))
+	}
+	if err != nil && j.err == nil {
+		j.err = err
+	}
+}
+
+// taskKey identifica uma imagem planejada pela URL e pelo caminho de destino
+func taskKey(task imageTask) string {
+	return task.URL + "\n" + task.Path
+}
+
+// plan numera as tasks e as grava no journal, devolvendo-as com o ID preenchido. Uma task que o
+// job já tem (mesma URL e caminho, ao planejar de novo um set num job reaproveitado) mantém o ID
+// e o histórico de falhas em vez de ser gravada outra vez.
+func (j *jobJournal) plan(tasks []imageTask) []imageTask {
+	if j == nil {
+		return tasks
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	planned := make([]imageTask, len(tasks))
+	for i, task := range tasks {
+		if id, ok := j.byPath[taskKey(task)]; ok {
+			task.ID = id
+		} else {
+			task.ID = len(j.tasks) + 1
+			j.tasks = append(j.tasks, task)
+			j.byPath[taskKey(task)] = task.ID
+			j.write(journalRecord{Type: "task", Task: &task})
+		}
+		planned[i] = task
+	}
+	return planned
+}
+
+// planSet grava as tasks de um set e marca o set como paginado
+func (j *jobJournal) planSet(code string, tasks []imageTask) []imageTask {
+	if j == nil {
+		return tasks
+	}
+	tasks = j.plan(tasks)
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.planned[code] = true
+	j.write(journalRecord{Type: "set", Code: code})
+	return tasks
+}
+
+func (j *jobJournal) markDone(taskID int, taskErr error) {
+	if j == nil || taskID == 0 {
+		return
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if taskErr != nil {
+		j.failures[taskID]++
+		j.write(journalRecord{Type: "failed", TaskID: taskID, Error: taskErr.Error()})
+		return
+	}
+	j.done[taskID] = true
+	j.write(journalRecord{Type: "done", TaskID: taskID})
+}
+
+// plannedTasks retorna todas as tasks planejadas, concluídas ou não
+func (j *jobJournal) plannedTasks() []imageTask {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return append([]imageTask(nil), j.tasks...)
+}
+
+// pendingTasks retorna as tasks planejadas que ainda não foram concluídas nem abandonadas
+func (j *jobJournal) pendingTasks() []imageTask {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	var pending []imageTask
+	for _, task := range j.tasks {
+		if !j.done[task.ID] && j.failures[task.ID] < maxTaskFailures {
+			pending = append(pending, task)
+		}
+	}
+	return pending
+}
+
+// abandonedTasks retorna as tasks que falharam maxTaskFailures vezes sem nunca concluir
+func (j *jobJournal) abandonedTasks() []imageTask {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	var abandoned []imageTask
+	for _, task := range j.tasks {
+		if !j.done[task.ID] && j.failures[task.ID] >= maxTaskFailures {
+			abandoned = append(abandoned, task)
+		}
+	}
+	return abandoned
+}
+
+// abandoned indica se a task já esgotou as tentativas
+func (j *jobJournal) abandoned(taskID int) bool {
+	if j == nil || taskID == 0 {
+		return false
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return !j.done[taskID] && j.failures[taskID] >= maxTaskFailures
+}
+
+// unplannedSets retorna os sets pedidos cuja paginação ainda não foi concluída
+func (j *jobJournal) unplannedSets() []string {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	var unplanned []string
+	for _, code := range j.Sets {
+		if !j.planned[strings.TrimSpace(strings.ToLower(code))] {
+			unplanned = append(unplanned, code)
+		}
+	}
+	return unplanned
+}
+
+// close fecha o arquivo e o remove se o job terminou sem pendências
+func (j *jobJournal) close() error {
+	if j == nil || j.file == nil {
+		return nil
+	}
+	finished := len(j.pendingTasks()) == 0 && len(j.unplannedSets()) == 0
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if err := j.file.Close(); err != nil && j.err == nil {
+		j.err = err
+	}
+	j.file = nil
+	if finished && j.err == nil {
+		return os.Remove(j.path)
+	}
+	return j.err
+}
+
+// remove descarta o journal de um job que não será retomado
+func (j *jobJournal) remove() error {
+	return os.Remove(j.path)
+}
+
+// summary descreve o job para listagens
+func (j *jobJournal) summary() string {
+	return fmt.Sprintf("%s  %s  (%d/%d imagens concluídas, %d sets por paginar)",
+		j.ID, j.Description, len(j.tasks)-len(j.pendingTasks()), len(j.tasks), len(j.unplannedSets()))
+}
+
+// resumeJob continua um job inacabado: pagina apenas os sets que faltaram e baixa as tasks pendentes.
+// sets pode ser nil; a lista só é buscada se houver sets por paginar.
+func (d *Downloader) resumeJob(ctx context.Context, job *jobJournal, sets []Set) downloadCompleteMsg {
+	if err := job.openForAppend(); err != nil {
+		return downloadCompleteMsg{success: false, message: err.Error(), failed: []string{job.ID}}
+	}
+	defer d.closeJob(job)
+
+	tasks := job.pendingTasks()
+	var completed, failed []string
+	planner := d.newPathPlanner()
+	planner.reserve(job.plannedTasks()) // inclusive as concluídas, cujos arquivos já existem
+
+	if unplanned := job.unplannedSets(); len(unplanned) > 0 {
+		if sets == nil {
+			var err error
+			if sets, err = d.fetchSets(ctx); err != nil {
+				d.logf("Erro ao buscar sets: %v", err)
+				return downloadCompleteMsg{success: false, message: err.Error(), failed: unplanned}
+			}
+		}
+		var newTasks []imageTask
+		newTasks, completed, failed = d.planSets(ctx, job, planner, sets, unplanned)
+		tasks = append(tasks, newTasks...)
+	}
+
+	d.logf("Retomando job %s: %d imagens pendentes", job.ID, len(tasks))
+	successCount := d.runTasks(ctx, tasks, job)
+
+	return downloadCompleteMsg{
+		success:           len(tasks) == 0 || successCount > 0,
+		message:           fmt.Sprintf("Job %s retomado: %d/%d imagens processadas", job.ID, successCount, len(tasks)),
+		completed:         completed,
+		failed:            failed,
+		cancelled:         ctx.Err() != nil,
+		variantsKept:      planner.kept,
+		variantsCollapsed: planner.collapsed,
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Idiomas de impressão do Scryfall (campo lang das cartas)
+var languageOptions = []string{"en", "es", "fr", "de", "it", "pt", "ja", "ko", "ru", "zhs", "zht", "he", "la", "grc", "ar", "sa", "ph"}
+
+// withLang acrescenta o filtro de idioma à consulta de um search_uri. Sem ele o Scryfall
+// devolve só as impressões em inglês.
+func withLang(searchURI, lang string) string {
+	u, err := url.Parse(searchURI)
+	if err != nil {
+		return searchURI
+	}
+	query := u.Query()
+	query.Set("q", query.Get("q")+" lang:"+lang)
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+// printKey identifica uma impressão independente do idioma
+func printKey(card Card) string {
+	return card.Set + "/" + card.CollectorNumber
+}
+
+// fetchLocalizedCards busca as cartas de um search_uri no idioma configurado. Com fallback, as
+// cartas sem impressão nesse idioma vêm em inglês, mantendo a ordem da lista em inglês.
+func (d *Downloader) fetchLocalizedCards(ctx context.Context, searchURI string) ([]Card, error) {
+	if d.lang == "en" {
+		return d.fetchSetCards(ctx, searchURI)
+	}
+	localized, err := d.fetchSetCards(ctx, withLang(searchURI, d.lang))
+	if err != nil || !d.langFallback {
+		return localized, err
+	}
+	english, err := d.fetchSetCards(ctx, searchURI)
+	if err != nil {
+		return nil, err
+	}
+
+	byPrint := make(map[string]Card, len(localized))
+	for _, card := range localized {
+		byPrint[printKey(card)] = card
+	}
+	cards := make([]Card, 0, len(english))
+	missing := 0
+	for _, card := range english {
+		if localizedCard, ok := byPrint[printKey(card)]; ok {
+			cards = append(cards, localizedCard)
+			delete(byPrint, printKey(card))
+		} else {
+			cards = append(cards, card)
+			missing++
+		}
+	}
+	// Impressões que só existem no idioma pedido
+	for _, card := range localized {
+		if _, ok := byPrint[printKey(card)]; ok {
+			cards = append(cards, card)
+		}
+	}
+	if missing > 0 {
+		d.logf("%d cartas sem impressão em %s, usando inglês", missing, d.lang)
+	}
+	return cards, nil
+}
+
+// localizeCard troca uma impressão pela mesma impressão no idioma configurado
+func (d *Downloader) localizeCard(ctx context.Context, card *Card) (*Card, error) {
+	if d.lang == "en" || card.Lang == d.lang {
+		return card, nil
+	}
+	localized, err := d.fetchCardURL(ctx, d.apiURL(fmt.Sprintf("/cards/%s/%s/%s", url.PathEscape(card.Set), url.PathEscape(card.CollectorNumber), d.lang)))
+	if err == nil {
+		return localized, nil
+	}
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	if d.langFallback {
+		return card, nil
+	}
+	return nil, fmt.Errorf("%s (%s #%s) não tem impressão em %s", card.Name, strings.ToUpper(card.Set), card.CollectorNumber, d.lang)
+}
@@ -1,982 +1,2145 @@
-package main
-
-import (
-	"encoding/json"
-	"fmt"
-	"io"
-	"net/http"
-	"os"
-	"path/filepath"
-	"sort"
-	"strconv"
-	"strings"
-	"sync"
-	"sync/atomic"
-	"time"
-
-	"github.com/charmbracelet/bubbles/list"
-	"github.com/charmbracelet/bubbles/progress"
-	"github.com/charmbracelet/bubbles/spinner"
-	"github.com/charmbracelet/bubbles/textinput"
-	tea "github.com/charmbracelet/bubbletea"
-	"github.com/charmbracelet/lipgloss"
-)
-
-// Estilos globais
-var (
-	titleStyle    = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#FAFAFA")).Background(lipgloss.Color("#7D56F4")).Padding(0, 1)
-	helpStyle     = lipgloss.NewStyle().Foreground(lipgloss.Color("#626262"))
-	errorStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("#FF5555"))
-	successStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("#50FA7B"))
-	infoStyle     = lipgloss.NewStyle().Foreground(lipgloss.Color("#8BE9FD"))
-	warningStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("#FFB86C"))
-	selectedStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#FF79C6"))
-)
-
-// API structs
-type SetData struct {
-	Data []Set `json:"data"`
-}
-type Set struct {
-	Code       string `json:"code"`
-	Name       string `json:"name"`
-	SearchURI  string `json:"search_uri"`
-	SetType    string `json:"set_type"`
-	CardCount  int    `json:"card_count"`
-	ReleasedAt string `json:"released_at"`
-	Digital    bool   `json:"digital"`
-}
-
-type CardData struct {
-	Data []Card `json:"data"`
-}
-type Card struct {
-	Name            string            `json:"name"`
-	Layout          string            `json:"layout"`
-	ImageURIs       map[string]string `json:"image_uris"`
-	CardFaces       []CardFace        `json:"card_faces"`
-	Set             string            `json:"set"`
-	PrintsSearchURI string            `json:"prints_search_uri"`
-}
-type CardFace struct {
-	Name      string            `json:"name"`
-	ImageURIs map[string]string `json:"image_uris"`
-}
-
-// Messages
-type setListMsg []Set
-type downloadCompleteMsg struct {
-	success           bool
-	message           string
-	completed, failed []string
-}
-type errorMsg struct{ err error }
-type progressUpdateMsg struct {
-	current, total int
-	message        string
-}
-
-// Estados
-type state int
-
-const (
-	menuState state = iota
-	setListState
-	setSearchState
-	setDownloadState
-	cardDownloadState
-	configState
-)
-
-// List item
-type setItem struct{ set Set }
-
-func (s setItem) FilterValue() string { return s.set.Code + " " + s.set.Name + " " + s.set.SetType }
-func (s setItem) Title() string {
-	digital := ""
-	if s.set.Digital {
-		digital = " 💻"
-	}
-	return fmt.Sprintf("%s - %s%s", strings.ToUpper(s.set.Code), s.set.Name, digital)
-}
-func (s setItem) Description() string {
-	return fmt.Sprintf("%s | %d cartas | %s", s.set.SetType, s.set.CardCount, s.set.ReleasedAt)
-}
-
-// Model principal
-type model struct {
-	state          state
-	spinner        spinner.Model
-	textInput      textinput.Model
-	searchInput    textinput.Model
-	progress       progress.Model
-	setList        list.Model
-	sets           []Set
-	currentMenu    int
-	menuOptions    []string
-	downloadDir    string
-	quality        string
-	maxWorkers     int
-	logs           []string
-	downloader     *Downloader
-	totalTasks     int64
-	completedTasks int64
-}
-
-type Downloader struct {
-	client      *http.Client
-	maxWorkers  int
-	downloadDir string
-	quality     string
-}
-
-func NewDownloader(maxWorkers int, downloadDir, quality string) *Downloader {
-	return &Downloader{
-		client:      &http.Client{Timeout: 30 * time.Second},
-		maxWorkers:  maxWorkers,
-		downloadDir: downloadDir,
-		quality:     quality,
-	}
-}
-
-func (m *model) updateDownloaderConfig() {
-	m.downloader.downloadDir = m.downloadDir
-	m.downloader.quality = m.quality
-	m.downloader.maxWorkers = m.maxWorkers
-}
-
-func (d *Downloader) fetchSets() ([]Set, error) {
-	resp, err := d.client.Get("https://api.scryfall.com/sets")
-	if err != nil {
-		return nil, fmt.Errorf("erro ao buscar sets: %w", err)
-	}
-	defer resp.Body.Close()
-
-	var setData SetData
-	if err := json.NewDecoder(resp.Body).Decode(&setData); err != nil {
-		return nil, fmt.Errorf("erro ao decodificar sets: %w", err)
-	}
-
-	sort.Slice(setData.Data, func(i, j int) bool { return setData.Data[i].ReleasedAt > setData.Data[j].ReleasedAt })
-	return setData.Data, nil
-}
-
-func (d *Downloader) fetchSetCards(searchURI string) ([]Card, error) {
-	allCards := []Card{}
-	currentURL := searchURI
-
-	// Loop para pegar todas as páginas
-	for currentURL != "" {
-		resp, err := d.client.Get(currentURL)
-		if err != nil {
-			return nil, fmt.Errorf("erro ao buscar cartas do set: %w", err)
-		}
-
-		var result struct {
-			Data     []Card `json:"data"`
-			HasMore  bool   `json:"has_more"`
-			NextPage string `json:"next_page"`
-		}
-
-		if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-			resp.Body.Close()
-			return nil, fmt.Errorf("erro ao decodificar cartas: %w", err)
-		}
-		resp.Body.Close()
-
-		// Adicionar cartas desta página
-		allCards = append(allCards, result.Data...)
-
-		// Verificar se há mais páginas
-		if result.HasMore && result.NextPage != "" {
-			currentURL = result.NextPage
-			// Pequena pausa para não sobrecarregar a API
-			time.Sleep(100 * time.Millisecond)
-		} else {
-			currentURL = ""
-		}
-	}
-
-	return allCards, nil
-}
-
-func (d *Downloader) fetchCard(cardName string) (*Card, error) {
-	cardName = strings.ReplaceAll(strings.ReplaceAll(strings.ReplaceAll(strings.ReplaceAll(cardName, " ", "+"), "/", "+"), ",", "+"), "'", "")
-	url := fmt.Sprintf("https://api.scryfall.com/cards/named?fuzzy=%s", cardName)
-	resp, err := d.client.Get(url)
-	if err != nil {
-		return nil, fmt.Errorf("erro ao buscar carta: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != 200 {
-		return nil, fmt.Errorf("carta não encontrada")
-	}
-
-	var card Card
-	if err := json.NewDecoder(resp.Body).Decode(&card); err != nil {
-		return nil, fmt.Errorf("erro ao decodificar carta: %w", err)
-	}
-	return &card, nil
-}
-
-func (d *Downloader) downloadImage(url, fileName, setCode string) error {
-	setDir := filepath.Join(d.downloadDir, strings.ToUpper(setCode))
-	if err := os.MkdirAll(setDir, 0755); err != nil {
-		return fmt.Errorf("erro ao criar diretório %s: %w", setDir, err)
-	}
-
-	// Limpeza mais robusta de caracteres inválidos
-	fileName = strings.TrimSpace(fileName)
-	invalidChars := []string{":", "?", "\"", "*", "<", ">", "|", "/", "\\"}
-	for _, char := range invalidChars {
-		fileName = strings.ReplaceAll(fileName, char, "")
-	}
-
-	// Remover espaços duplos e caracteres especiais adicionais
-	fileName = strings.ReplaceAll(fileName, "  ", " ")
-	fileName = strings.ReplaceAll(fileName, "'", "")
-	fileName = strings.ReplaceAll(fileName, ",", "")
-
-	filePath := filepath.Join(setDir, fileName+".full.jpg")
-
-	if _, err := os.Stat(filePath); err == nil {
-		return nil // Já existe
-	}
-
-	resp, err := d.client.Get(url)
-	if err != nil {
-		return fmt.Errorf("erro ao baixar %s: %w", fileName, err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != 200 {
-		return fmt.Errorf("falha no download %s: HTTP %d", fileName, resp.StatusCode)
-	}
-
-	file, err := os.Create(filePath)
-	if err != nil {
-		return fmt.Errorf("erro ao criar arquivo %s: %w", filePath, err)
-	}
-	defer file.Close()
-
-	_, err = io.Copy(file, resp.Body)
-	return err
-}
-
-func (d *Downloader) processCard(card Card) []func() error {
-	var tasks []func() error
-
-	// Função helper para adicionar task de download
-	addDownloadTask := func(imageURL, cardName string) {
-		if imageURL != "" {
-			tasks = append(tasks, func() error {
-				return d.downloadImage(imageURL, cardName, card.Set)
-			})
-		}
-	}
-
-	// Função para tentar diferentes qualidades
-	tryDownloadWithFallback := func(imageURIs map[string]string, cardName string) {
-		qualities := []string{d.quality, "normal", "small", "large"} // Tenta a qualidade configurada primeiro, depois fallbacks
-
-		for _, quality := range qualities {
-			if imageURL, ok := imageURIs[quality]; ok {
-				addDownloadTask(imageURL, cardName)
-				return // Para no primeiro que encontrar
-			}
-		}
-	}
-
-	switch card.Layout {
-	case "adventure":
-		// Cartas Adventure (uma face principal)
-		name := strings.Split(card.Name, " //")[0]
-		tryDownloadWithFallback(card.ImageURIs, name)
-
-	case "transform", "modal_dfc", "reversible_card", "double_faced_token":
-		// Cartas de dupla face
-		for _, face := range card.CardFaces {
-			if len(face.ImageURIs) > 0 {
-				tryDownloadWithFallback(face.ImageURIs, face.Name)
-			}
-		}
-
-	case "split", "flip":
-		// Cartas split/flip - geralmente uma imagem só
-		if len(card.ImageURIs) > 0 {
-			tryDownloadWithFallback(card.ImageURIs, card.Name)
-		} else {
-			// Fallback para faces individuais se necessário
-			for _, face := range card.CardFaces {
-				if len(face.ImageURIs) > 0 {
-					tryDownloadWithFallback(face.ImageURIs, face.Name)
-				}
-			}
-		}
-
-	default:
-		// Cartas normais e outros layouts
-		if strings.Contains(card.Name, "//") && len(card.CardFaces) > 0 {
-			// Tem faces separadas
-			for _, face := range card.CardFaces {
-				if len(face.ImageURIs) > 0 {
-					tryDownloadWithFallback(face.ImageURIs, face.Name)
-				}
-			}
-		} else {
-			// Carta normal
-			if len(card.ImageURIs) > 0 {
-				tryDownloadWithFallback(card.ImageURIs, card.Name)
-			}
-		}
-	}
-
-	return tasks
-}
-
-func initialModel() model {
-	s := spinner.New()
-	s.Spinner = spinner.Dot
-	s.Style = lipgloss.NewStyle().Foreground(lipgloss.Color("205"))
-
-	ti := textinput.New()
-	ti.Placeholder = "Digite aqui..."
-	ti.CharLimit = 200
-	ti.Width = 60
-
-	si := textinput.New()
-	si.Placeholder = "Buscar sets..."
-	si.CharLimit = 50
-	si.Width = 40
-
-	prog := progress.New(progress.WithDefaultGradient())
-
-	setList := list.New([]list.Item{}, list.NewDefaultDelegate(), 70, 20)
-	setList.Title = "Sets Disponíveis"
-	setList.SetShowStatusBar(true)
-	setList.SetFilteringEnabled(true)
-
-	return model{
-		state:       menuState,
-		spinner:     s,
-		textInput:   ti,
-		searchInput: si,
-		progress:    prog,
-		setList:     setList,
-		currentMenu: 0,
-		menuOptions: []string{"🎴 Download por Set", "🃏 Download por Carta", "📋 Listar/Buscar Sets", "⚙️ Configurações", "🚪 Sair"},
-		downloadDir: "./downloads",
-		quality:     "large",
-		maxWorkers:  10,
-		logs:        []string{},
-		downloader:  NewDownloader(10, "./downloads", "large"),
-	}
-}
-
-func (m model) Init() tea.Cmd { return m.spinner.Tick }
-
-func (m model) tickProgress() tea.Cmd {
-	return tea.Tick(time.Millisecond*100, func(t time.Time) tea.Msg {
-		current := atomic.LoadInt64(&m.completedTasks)
-		total := atomic.LoadInt64(&m.totalTasks)
-		return progressUpdateMsg{current: int(current), total: int(total), message: fmt.Sprintf("Baixando... %d/%d", current, total)}
-	})
-}
-
-func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
-	switch msg := msg.(type) {
-	case tea.KeyMsg:
-		switch m.state {
-		case menuState:
-			switch msg.String() {
-			case "up", "k":
-				if m.currentMenu > 0 {
-					m.currentMenu--
-				}
-			case "down", "j":
-				if m.currentMenu < len(m.menuOptions)-1 {
-					m.currentMenu++
-				}
-			case "enter":
-				switch m.currentMenu {
-				case 0: // Download por Set
-					m.state = setDownloadState
-					m.textInput.SetValue("")
-					m.textInput.Placeholder = "Códigos dos sets separados por vírgula (ex: dom,war,m21) ou 'ALL' para todos"
-					m.textInput.Focus()
-					if len(m.sets) == 0 {
-						return m, m.fetchSetsCmd()
-					}
-				case 1: // Download por Carta
-					m.state = cardDownloadState
-					m.textInput.SetValue("")
-					m.textInput.Placeholder = "Nome da carta (ex: Lightning Bolt)"
-					m.textInput.Focus()
-				case 2: // Listar Sets
-					m.state = setSearchState
-					m.searchInput.SetValue("")
-					m.searchInput.Focus()
-					if len(m.sets) == 0 {
-						return m, m.fetchSetsCmd()
-					} else {
-						m.updateSetList("")
-					}
-				case 3: // Configurações
-					m.state = configState
-					m.currentMenu = 0
-				case 4: // Sair
-					return m, tea.Quit
-				}
-			case "q", "ctrl+c":
-				return m, tea.Quit
-			}
-
-		case setSearchState:
-			switch msg.String() {
-			case "esc":
-				m.state = menuState
-				m.searchInput.Blur()
-			default:
-				var cmd tea.Cmd
-				m.searchInput, cmd = m.searchInput.Update(msg)
-				if msg.String() != "enter" {
-					m.updateSetList(m.searchInput.Value())
-				}
-				var listCmd tea.Cmd
-				m.setList, listCmd = m.setList.Update(msg)
-				return m, tea.Batch(cmd, listCmd)
-			}
-
-		case setDownloadState:
-			switch msg.String() {
-			case "enter":
-				input := strings.TrimSpace(m.textInput.Value())
-				if input != "" {
-					m.state = setListState
-					m.logs = []string{}
-					atomic.StoreInt64(&m.totalTasks, 0)
-					atomic.StoreInt64(&m.completedTasks, 0)
-
-					if strings.ToUpper(input) == "ALL" {
-						var allCodes []string
-						for _, set := range m.sets {
-							if !set.Digital {
-								allCodes = append(allCodes, set.Code)
-							}
-						}
-						m.logs = append(m.logs, fmt.Sprintf("🚀 Iniciando download de TODOS os sets (%d sets)", len(allCodes)))
-						return m, tea.Batch(m.spinner.Tick, m.tickProgress(), m.downloadMultipleSetsCmd(allCodes))
-					} else {
-						codes := strings.Split(input, ",")
-						var cleanCodes []string
-						for _, code := range codes {
-							if clean := strings.TrimSpace(code); clean != "" {
-								cleanCodes = append(cleanCodes, clean)
-							}
-						}
-						m.logs = append(m.logs, fmt.Sprintf("🚀 Iniciando download de %d sets: %s", len(cleanCodes), strings.Join(cleanCodes, ", ")))
-						return m, tea.Batch(m.spinner.Tick, m.tickProgress(), m.downloadMultipleSetsCmd(cleanCodes))
-					}
-				}
-			case "esc":
-				m.state = menuState
-				m.textInput.Blur()
-			default:
-				var cmd tea.Cmd
-				m.textInput, cmd = m.textInput.Update(msg)
-				return m, cmd
-			}
-
-		case cardDownloadState:
-			switch msg.String() {
-			case "enter":
-				if cardName := strings.TrimSpace(m.textInput.Value()); cardName != "" {
-					m.state = setListState
-					m.logs = []string{}
-					atomic.StoreInt64(&m.totalTasks, 0)
-					atomic.StoreInt64(&m.completedTasks, 0)
-					return m, tea.Batch(m.spinner.Tick, m.tickProgress(), m.downloadCardCmd(cardName))
-				}
-			case "esc":
-				m.state = menuState
-				m.textInput.Blur()
-			default:
-				var cmd tea.Cmd
-				m.textInput, cmd = m.textInput.Update(msg)
-				return m, cmd
-			}
-
-		case setListState:
-			if msg.String() == "esc" || msg.String() == "q" {
-				m.state = menuState
-			}
-
-		case configState:
-			switch msg.String() {
-			case "esc":
-				if m.textInput.Focused() {
-					m.textInput.Blur()
-				} else {
-					m.state = menuState
-				}
-			case "up", "k":
-				if !m.textInput.Focused() && m.currentMenu > 0 {
-					m.currentMenu--
-				}
-			case "down", "j":
-				if !m.textInput.Focused() && m.currentMenu < 3 {
-					m.currentMenu++
-				}
-			case "enter":
-				if m.textInput.Focused() {
-					// Processar o valor inserido
-					value := strings.TrimSpace(m.textInput.Value())
-					switch m.currentMenu {
-					case 0: // Pasta
-						if value != "" {
-							if err := os.MkdirAll(value, 0755); err != nil {
-								m.logs = append(m.logs, errorStyle.Render(fmt.Sprintf("❌ Erro ao acessar pasta: %v", err)))
-							} else {
-								m.downloadDir = value
-								m.updateDownloaderConfig()
-								m.logs = append(m.logs, successStyle.Render(fmt.Sprintf("✅ Pasta alterada para: %s", value)))
-							}
-						}
-					case 2: // Workers
-						if workers, err := strconv.Atoi(value); err == nil && workers > 0 && workers <= 50 {
-							m.maxWorkers = workers
-							m.updateDownloaderConfig()
-							m.logs = append(m.logs, successStyle.Render(fmt.Sprintf("✅ Workers alterados para: %d", workers)))
-						} else {
-							m.logs = append(m.logs, errorStyle.Render("⚠️ Número de workers deve ser entre 1 e 50"))
-						}
-					}
-					m.textInput.Blur()
-					// Limitar logs
-					if len(m.logs) > 10 {
-						m.logs = m.logs[len(m.logs)-10:]
-					}
-				} else {
-					// Não está editando, então iniciar edição
-					switch m.currentMenu {
-					case 0: // Pasta de download
-						m.textInput.SetValue(m.downloadDir)
-						m.textInput.Placeholder = "Caminho da pasta (ex: C:\\MinhasCartas)"
-						m.textInput.Focus()
-					case 1: // Qualidade
-						qualities := []string{"small", "normal", "large"}
-						currentIndex := 0
-						for i, q := range qualities {
-							if q == m.quality {
-								currentIndex = i
-								break
-							}
-						}
-						nextIndex := (currentIndex + 1) % len(qualities)
-						m.quality = qualities[nextIndex]
-						m.updateDownloaderConfig()
-						m.logs = append(m.logs, successStyle.Render(fmt.Sprintf("✅ Qualidade alterada para: %s", m.quality)))
-						if len(m.logs) > 10 {
-							m.logs = m.logs[len(m.logs)-10:]
-						}
-					case 2: // Workers
-						m.textInput.SetValue(strconv.Itoa(m.maxWorkers))
-						m.textInput.Placeholder = "Número de workers (1-50)"
-						m.textInput.Focus()
-					case 3: // Voltar
-						m.state = menuState
-					}
-				}
-			default:
-				if m.textInput.Focused() {
-					var cmd tea.Cmd
-					m.textInput, cmd = m.textInput.Update(msg)
-					return m, cmd
-				}
-			}
-		}
-
-	case setListMsg:
-		m.sets = []Set(msg)
-		if m.state == setSearchState {
-			m.updateSetList(m.searchInput.Value())
-		}
-
-	case progressUpdateMsg:
-		if msg.total > 0 {
-			progress := float64(msg.current) / float64(msg.total)
-			cmd := m.progress.SetPercent(progress)
-			return m, tea.Batch(cmd, m.tickProgress())
-		}
-		return m, m.tickProgress()
-
-	case downloadCompleteMsg:
-		m.logs = append(m.logs, "", successStyle.Render("🎉 DOWNLOAD COMPLETO!"), msg.message)
-		if len(msg.completed) > 0 {
-			m.logs = append(m.logs, successStyle.Render(fmt.Sprintf("✅ Sets baixados com sucesso (%d):", len(msg.completed))))
-			for _, code := range msg.completed {
-				m.logs = append(m.logs, successStyle.Render(fmt.Sprintf("  ✓ %s", strings.ToUpper(code))))
-			}
-		}
-		if len(msg.failed) > 0 {
-			m.logs = append(m.logs, errorStyle.Render(fmt.Sprintf("❌ Sets com falha (%d):", len(msg.failed))))
-			for _, code := range msg.failed {
-				m.logs = append(m.logs, errorStyle.Render(fmt.Sprintf("  ✗ %s", strings.ToUpper(code))))
-			}
-		}
-		if len(m.logs) > 20 {
-			m.logs = m.logs[len(m.logs)-20:]
-		}
-
-	case errorMsg:
-		m.logs = append(m.logs, errorStyle.Render(fmt.Sprintf("❌ Erro: %v", msg.err)))
-		if len(m.logs) > 15 {
-			m.logs = m.logs[1:]
-		}
-
-	default:
-		var cmd tea.Cmd
-		m.spinner, cmd = m.spinner.Update(msg)
-		return m, cmd
-	}
-
-	return m, nil
-}
-
-func (m *model) updateSetList(filter string) {
-	items := []list.Item{}
-	for _, set := range m.sets {
-		if filter == "" || strings.Contains(strings.ToLower(set.Code), strings.ToLower(filter)) ||
-			strings.Contains(strings.ToLower(set.Name), strings.ToLower(filter)) ||
-			strings.Contains(strings.ToLower(set.SetType), strings.ToLower(filter)) {
-			items = append(items, setItem{set: set})
-		}
-	}
-	m.setList.SetItems(items)
-}
-
-func (m model) View() string {
-	switch m.state {
-	case menuState:
-		return m.renderMenu()
-	case setSearchState:
-		return m.renderSetSearch()
-	case setDownloadState:
-		return m.renderSetDownloadInput()
-	case cardDownloadState:
-		return m.renderCardInput()
-	case setListState:
-		return m.renderDownload()
-	case configState:
-		return m.renderConfig()
-	default:
-		return "Estado desconhecido"
-	}
-}
-
-func (m model) renderMenu() string {
-	s := titleStyle.Render("🎴 MTG Card Downloader") + "\n\n"
-	for i, option := range m.menuOptions {
-		cursor := " "
-		if m.currentMenu == i {
-			cursor = selectedStyle.Render("▶")
-		}
-		s += fmt.Sprintf("%s %s\n", cursor, option)
-	}
-	s += "\n" + helpStyle.Render("↑/↓: navegar • enter: selecionar • q: sair")
-	return s
-}
-
-func (m model) renderSetSearch() string {
-	s := titleStyle.Render("📋 Listar/Buscar Sets") + "\n\n"
-	if len(m.sets) == 0 {
-		s += m.spinner.View() + " Carregando sets...\n\n"
-	} else {
-		s += "🔍 Buscar: " + m.searchInput.View() + "\n\n"
-		s += m.setList.View()
-		s += "\n" + infoStyle.Render(fmt.Sprintf("Total: %d sets | Mostrando: %d", len(m.sets), len(m.setList.Items())))
-	}
-	s += "\n" + helpStyle.Render("digite para buscar • esc: voltar")
-	return s
-}
-
-func (m model) renderSetDownloadInput() string {
-	s := titleStyle.Render("🎴 Download por Set(s)") + "\n\n"
-	s += "Digite os códigos dos sets:\n" + m.textInput.View() + "\n\n"
-	s += infoStyle.Render("💡 Dicas:") + "\n"
-	s += "  • Sets únicos: dom\n  • Múltiplos sets: dom,war,m21\n  • Todos os sets: ALL\n\n"
-
-	if len(m.sets) > 0 {
-		s += infoStyle.Render("🆕 Sets mais recentes:") + "\n"
-		for i, set := range m.sets[:min(5, len(m.sets))] {
-			digital := ""
-			if set.Digital {
-				digital = " 💻"
-			}
-			s += fmt.Sprintf("  %s - %s%s (%d cartas)\n", strings.ToUpper(set.Code), set.Name, digital, set.CardCount)
-			if i >= 4 {
-				break
-			}
-		}
-		s += "\n"
-	}
-
-	s += warningStyle.Render("⚠️ 'ALL' baixará TODOS os sets (pode demorar muito!)") + "\n\n"
-	s += helpStyle.Render("enter: confirmar • esc: voltar")
-	return s
-}
-
-func (m model) renderCardInput() string {
-	s := titleStyle.Render("🃏 Download por Carta") + "\n\n"
-	s += "Digite o nome da carta:\n" + m.textInput.View() + "\n\n"
-	s += infoStyle.Render("💡 Exemplo: Lightning Bolt, Black Lotus, Jace, etc.") + "\n\n"
-	s += helpStyle.Render("enter: confirmar • esc: voltar")
-	return s
-}
-
-func (m model) renderDownload() string {
-	s := titleStyle.Render("📥 Download em Progresso") + "\n\n"
-
-	current := atomic.LoadInt64(&m.completedTasks)
-	total := atomic.LoadInt64(&m.totalTasks)
-
-	if total > 0 {
-		percent := float64(current) / float64(total)
-		s += fmt.Sprintf("Progresso: %d/%d (%.1f%%)\n", current, total, percent*100)
-		s += m.progress.View() + "\n\n"
-	} else {
-		s += m.spinner.View() + " Preparando download...\n\n"
-	}
-
-	if len(m.logs) > 0 {
-		s += infoStyle.Render("📋 Log de Atividades:") + "\n"
-		startIndex := 0
-		if len(m.logs) > 12 {
-			startIndex = len(m.logs) - 12
-		}
-
-		for i := startIndex; i < len(m.logs); i++ {
-			log := m.logs[i]
-			if strings.HasPrefix(log, "✅") || strings.HasPrefix(log, "✓") {
-				s += successStyle.Render(log) + "\n"
-			} else if strings.HasPrefix(log, "❌") || strings.HasPrefix(log, "✗") {
-				s += errorStyle.Render(log) + "\n"
-			} else if strings.HasPrefix(log, "🚀") || strings.HasPrefix(log, "🎉") {
-				s += warningStyle.Render(log) + "\n"
-			} else {
-				s += log + "\n"
-			}
-		}
-	}
-
-	s += "\n" + helpStyle.Render("esc: voltar ao menu")
-	return s
-}
-
-func (m model) renderConfig() string {
-	s := titleStyle.Render("⚙️ Configurações") + "\n\n"
-
-	options := []string{
-		fmt.Sprintf("📁 Pasta de Download: %s", m.downloadDir),
-		fmt.Sprintf("🎨 Qualidade: %s", m.quality),
-		fmt.Sprintf("⚡ Workers: %d", m.maxWorkers),
-		"🔙 Voltar",
-	}
-
-	for i, option := range options {
-		cursor := " "
-		if m.currentMenu == i {
-			cursor = selectedStyle.Render("▶")
-		}
-		s += fmt.Sprintf("%s %s\n", cursor, option)
-	}
-
-	if m.textInput.Focused() {
-		s += "\n" + infoStyle.Render("Digite o novo valor:") + "\n" + m.textInput.View()
-	}
-
-	s += "\n\n" + infoStyle.Render("💡 Dicas:") + "\n"
-	s += "  • Pasta: Use caminho completo (ex: C:\\MinhasCartas)\n"
-	s += "  • Qualidade: small (menor), normal (média), large (alta)\n"
-	s += "  • Workers: Número de downloads simultâneos (1-50)\n\n"
-
-	if len(m.logs) > 0 && m.currentMenu < 3 {
-		s += infoStyle.Render("📋 Últimas alterações:") + "\n"
-		startIndex := len(m.logs) - 3
-		if startIndex < 0 {
-			startIndex = 0
-		}
-		for i := startIndex; i < len(m.logs); i++ {
-			s += m.logs[i] + "\n"
-		}
-		s += "\n"
-	}
-
-	s += helpStyle.Render("↑/↓: navegar • enter: editar • esc: voltar")
-	return s
-}
-
-func (m model) fetchSetsCmd() tea.Cmd {
-	return func() tea.Msg {
-		sets, err := m.downloader.fetchSets()
-		if err != nil {
-			return errorMsg{err}
-		}
-		return setListMsg(sets)
-	}
-}
-
-func (m model) downloadMultipleSetsCmd(setCodes []string) tea.Cmd {
-	return func() tea.Msg {
-		var completed, failed []string
-		var allTasks []func() error
-
-		for _, setCode := range setCodes {
-			setCode = strings.TrimSpace(strings.ToLower(setCode))
-
-			var targetSet *Set
-			for _, set := range m.sets {
-				if strings.EqualFold(set.Code, setCode) {
-					targetSet = &set
-					break
-				}
-			}
-
-			if targetSet == nil {
-				failed = append(failed, setCode)
-				continue
-			}
-
-			cards, err := m.downloader.fetchSetCards(targetSet.SearchURI)
-			if err != nil {
-				failed = append(failed, setCode)
-				continue
-			}
-
-			for _, card := range cards {
-				tasks := m.downloader.processCard(card)
-				allTasks = append(allTasks, tasks...)
-			}
-		}
-
-		atomic.StoreInt64(&m.totalTasks, int64(len(allTasks)))
-		atomic.StoreInt64(&m.completedTasks, 0)
-
-		if len(allTasks) == 0 {
-			return downloadCompleteMsg{success: false, message: "Nenhuma tarefa para executar", completed: []string{}, failed: setCodes}
-		}
-
-		semaphore := make(chan struct{}, m.maxWorkers)
-		var wg sync.WaitGroup
-		var successCount int64
-
-		for _, task := range allTasks {
-			wg.Add(1)
-			go func(t func() error) {
-				defer wg.Done()
-				semaphore <- struct{}{}
-				defer func() { <-semaphore }()
-
-				if err := t(); err == nil {
-					atomic.AddInt64(&successCount, 1)
-				}
-				atomic.AddInt64(&m.completedTasks, 1)
-			}(task)
-		}
-
-		wg.Wait()
-
-		for _, setCode := range setCodes {
-			setCode = strings.TrimSpace(strings.ToLower(setCode))
-			var targetSet *Set
-			for _, set := range m.sets {
-				if strings.EqualFold(set.Code, setCode) {
-					targetSet = &set
-					break
-				}
-			}
-			if targetSet != nil {
-				completed = append(completed, setCode)
-			}
-		}
-
-		successMsg := fmt.Sprintf("Download finalizado: %d imagens processadas", successCount)
-		if len(setCodes) > 1 {
-			successMsg = fmt.Sprintf("Download de %d sets finalizado: %d imagens processadas", len(setCodes), successCount)
-		}
-
-		return downloadCompleteMsg{
-			success:   successCount > 0,
-			message:   successMsg,
-			completed: completed,
-			failed:    failed,
-		}
-	}
-}
-
-func (m model) downloadCardCmd(cardName string) tea.Cmd {
-	return func() tea.Msg {
-		card, err := m.downloader.fetchCard(cardName)
-		if err != nil {
-			return errorMsg{err}
-		}
-
-		prints, err := m.downloader.fetchSetCards(card.PrintsSearchURI)
-		if err != nil {
-			return errorMsg{err}
-		}
-
-		var allTasks []func() error
-		for _, print := range prints {
-			tasks := m.downloader.processCard(print)
-			allTasks = append(allTasks, tasks...)
-		}
-
-		atomic.StoreInt64(&m.totalTasks, int64(len(allTasks)))
-		atomic.StoreInt64(&m.completedTasks, 0)
-
-		semaphore := make(chan struct{}, m.maxWorkers)
-		var wg sync.WaitGroup
-		var successCount int64
-
-		for _, task := range allTasks {
-			wg.Add(1)
-			go func(t func() error) {
-				defer wg.Done()
-				semaphore <- struct{}{}
-				defer func() { <-semaphore }()
-
-				if err := t(); err == nil {
-					atomic.AddInt64(&successCount, 1)
-				}
-				atomic.AddInt64(&m.completedTasks, 1)
-			}(task)
-		}
-
-		wg.Wait()
-
-		successMsg := fmt.Sprintf("✅ %d/%d imagens baixadas para '%s'", successCount, len(allTasks), card.Name)
-		return downloadCompleteMsg{
-			success:   successCount > 0,
-			message:   successMsg,
-			completed: []string{card.Name},
-			failed:    []string{},
-		}
-	}
-}
-
-func min(a, b int) int {
-	if a < b {
-		return a
-	}
-	return b
-}
-
-func main() {
-	p := tea.NewProgram(initialModel(), tea.WithAltScreen())
-	if _, err := p.Run(); err != nil {
-		fmt.Printf("Erro: %v", err)
-		os.Exit(1)
-	}
-}
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/bubbles/progress"
+	"github.com/charmbracelet/bubbles/spinner"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Estilos globais
+var (
+	titleStyle    = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#FAFAFA")).Background(lipgloss.Color("#7D56F4")).Padding(0, 1)
+	helpStyle     = lipgloss.NewStyle().Foreground(lipgloss.Color("#626262"))
+	errorStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("#FF5555"))
+	successStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("#50FA7B"))
+	infoStyle     = lipgloss.NewStyle().Foreground(lipgloss.Color("#8BE9FD"))
+	warningStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("#FFB86C"))
+	selectedStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#FF79C6"))
+)
+
+// API structs
+type SetData struct {
+	Data []Set `json:"data"`
+}
+type Set struct {
+	Code       string `json:"code"`
+	Name       string `json:"name"`
+	SearchURI  string `json:"search_uri"`
+	SetType    string `json:"set_type"`
+	CardCount  int    `json:"card_count"`
+	ReleasedAt string `json:"released_at"`
+
+	ParentSetCode string `json:"parent_set_code"`
+	Digital       bool   `json:"digital"`
+}
+
+type CardData struct {
+	Data []Card `json:"data"`
+}
+type Card struct {
+	ID              string            `json:"id"`
+	Name            string            `json:"name"`
+	Layout          string            `json:"layout"`
+	ImageURIs       map[string]string `json:"image_uris"`
+	CardFaces       []CardFace        `json:"card_faces"`
+	Set             string            `json:"set"`
+	CollectorNumber string            `json:"collector_number"`
+	PrintsSearchURI string            `json:"prints_search_uri"`
+	OracleID        string            `json:"oracle_id"`
+	Lang            string            `json:"lang"`
+	Rarity          string            `json:"rarity"`
+	Artist          string            `json:"artist"`
+	AllParts        []RelatedCard     `json:"all_parts"`
+}
+type CardFace struct {
+	Name      string            `json:"name"`
+	ImageURIs map[string]string `json:"image_uris"`
+	Artist    string            `json:"artist"`
+}
+
+// Messages
+type setListMsg []Set
+type downloadCompleteMsg struct {
+	success           bool
+	message           string
+	completed, failed []string
+	skipped           []string // entradas ignoradas, ex: linhas não reconhecidas da decklist
+	cancelled         bool     // interrompido pelo usuário; o que faltou fica no journal
+
+	// Artes alternativas que cairiam no mesmo arquivo: mantidas com outro nome ou descartadas
+	variantsKept, variantsCollapsed int
+}
+
+// searchCountMsg traz o total de cartas de uma busca, para a prévia
+type searchCountMsg struct {
+	query string
+	total int
+}
+
+// Sugestões de nome de carta: o tick espera o usuário parar de digitar antes de consultar a API
+type autocompleteTickMsg struct{ query string }
+type suggestionsMsg struct {
+	query string
+	names []string
+}
+type ambiguousCardMsg struct {
+	name  string
+	names []string
+}
+type errorMsg struct{ err error }
+type jobListMsg []*jobJournal
+type progressUpdateMsg struct {
+	current, total int
+	message        string
+	logs           []string // mensagens do Downloader desde o último tick
+}
+
+// Endereço padrão da API do Scryfall
+const defaultAPIBase = "https://api.scryfall.com"
+
+// Estados
+type state int
+
+// Itens da tela de configurações
+const (
+	configDownloadDir = iota
+	configQuality
+	configNameTemplate
+	configPreset
+	configVariants
+	configFaceNames
+	configCardBack
+	configRelated
+	configLang
+	configLangFallback
+	configUseCatalog
+	configWorkers
+	configAPIBase
+	configRetries
+	configRetryDelay
+	configAPIRate
+	configImageRate
+	configBack
+)
+
+const (
+	menuState state = iota
+	setListState
+	setSearchState
+	setDownloadState
+	cardDownloadState
+	searchDownloadState
+	deckImportState
+	jobsState
+	configState
+)
+
+// Itens do menu principal
+const (
+	menuSetDownload = iota
+	menuCardDownload
+	menuSearchDownload
+	menuDeckImport
+	menuResumeJobs
+	menuSetSearch
+	menuConfig
+	menuQuit
+)
+
+// List item
+type setItem struct {
+	set      Set
+	selected bool
+}
+
+func (s setItem) FilterValue() string { return s.set.Code + " " + s.set.Name + " " + s.set.SetType }
+func (s setItem) Title() string {
+	digital := ""
+	if s.set.Digital {
+		digital = " 💻"
+	}
+	check := "⬜"
+	if s.selected {
+		check = "✅"
+	}
+	return fmt.Sprintf("%s %s - %s%s", check, strings.ToUpper(s.set.Code), s.set.Name, digital)
+}
+func (s setItem) Description() string {
+	return fmt.Sprintf("%s | %d cartas | %s", s.set.SetType, s.set.CardCount, s.set.ReleasedAt)
+}
+
+// Model principal
+type model struct {
+	state       state
+	spinner     spinner.Model
+	textInput   textinput.Model
+	searchInput textinput.Model
+	progress    progress.Model
+	setList     list.Model
+	sets        []Set
+	currentMenu int
+	menuOptions []string
+	config      Config
+	configPath  string
+	jobs        []*jobJournal
+	jobCursor   int
+
+	// Busca cuja prévia está na tela, aguardando confirmação
+	searchQuery string
+	searchHits  int
+
+	// Sugestões sob o campo do nome da carta; cursor -1 usa o texto digitado
+	suggestions      []string
+	suggestionCursor int
+	ambiguousName    string // nome que a busca fuzzy não conseguiu resolver
+
+	// Sets marcados na tela de busca
+	selectedSets map[string]bool
+
+	// Sets escolhidos por ALL, aguardando confirmação depois da prévia
+	pendingCodes []string
+	pendingCards int
+	pendingExpr  string
+	logs         []string
+	downloader   *Downloader
+
+	// Download em andamento: cancel é nil quando não há nenhum
+	cancel        context.CancelFunc
+	confirmCancel bool
+}
+
+type Downloader struct {
+	client      *http.Client
+	maxWorkers  int
+	downloadDir string
+	imageTypes  []string
+	apiBase     string
+
+	nameTemplate     string
+	numberedVariants bool
+	variants         string
+	pairedFaces      bool
+	cardBack         bool
+	cardBackURL      string
+	includeRelated   bool
+	relatedFolder    string
+	lang             string
+	langFallback     bool
+
+	useCatalog bool
+	catalogDir string
+	catalog    *catalog // aberto na primeira consulta
+	catalogMu  sync.Mutex
+
+	cache   *httpCache // nil com o cache desligado
+	offline bool
+
+	retry retryPolicy
+
+	// Limites separados para a API (etiqueta do Scryfall) e para o CDN de imagens
+	apiLimiter   *rateLimiter
+	imageLimiter *rateLimiter
+
+	pause pauseGate
+
+	// Progresso do download atual, lido pela TUI e pela CLI
+	totalTasks     int64
+	completedTasks int64
+	retryCount     int64
+
+	// Saída para mensagens de progresso (stderr no modo CLI). Sem saída, as mensagens
+	// ficam em logBuffer até a TUI buscá-las com drainLogs.
+	logOutput io.Writer
+	logMu     sync.Mutex
+	logBuffer []string
+}
+
+func NewDownloader(cfg Config) *Downloader {
+	d := &Downloader{client: &http.Client{Timeout: 30 * time.Second}}
+	d.applyConfig(cfg)
+	return d
+}
+
+func (d *Downloader) applyConfig(cfg Config) {
+	d.maxWorkers = cfg.MaxWorkers
+	d.downloadDir = cfg.DownloadDir
+	d.imageTypes = parseImageTypes(cfg.Quality)
+	d.apiBase = strings.TrimRight(cfg.APIBase, "/")
+	d.nameTemplate = cfg.NameTemplate
+	d.numberedVariants = false
+	d.variants = cfg.Variants
+	d.pairedFaces = cfg.FaceNames == "paired"
+	d.cardBack = cfg.CardBack
+	d.cardBackURL = cfg.CardBackURL
+	d.includeRelated = cfg.IncludeRelated
+	d.relatedFolder = strings.Trim(filepath.ToSlash(cfg.RelatedFolder), "/")
+	d.lang = cfg.Lang
+	d.langFallback = cfg.LangFallback
+	d.useCatalog = cfg.UseCatalog
+	d.catalogDir = cfg.CatalogDir
+	d.offline = cfg.Offline
+	d.cache = nil
+	if cfg.Cache {
+		d.cache = &httpCache{dir: cfg.CacheDir, ttl: time.Duration(cfg.CacheTTLMinutes) * time.Minute}
+	}
+	if preset := findExportPreset(cfg.Preset); preset != nil {
+		d.nameTemplate = preset.template
+		d.numberedVariants = preset.numbered
+	}
+	d.retry = newRetryPolicy(cfg)
+
+	if d.apiLimiter == nil {
+		d.apiLimiter = newRateLimiter(cfg.APIRate, 1)
+		d.imageLimiter = newRateLimiter(cfg.ImageRate, int(cfg.ImageRate))
+	} else {
+		d.apiLimiter.setRate(cfg.APIRate, 1)
+		d.imageLimiter.setRate(cfg.ImageRate, int(cfg.ImageRate))
+	}
+}
+
+func (d *Downloader) logf(format string, args ...interface{}) {
+	if d.logOutput != nil {
+		fmt.Fprintf(d.logOutput, format+"\n", args...)
+		return
+	}
+
+	d.logMu.Lock()
+	defer d.logMu.Unlock()
+	d.logBuffer = append(d.logBuffer, fmt.Sprintf(format, args...))
+	if len(d.logBuffer) > 100 {
+		d.logBuffer = d.logBuffer[len(d.logBuffer)-100:]
+	}
+}
+
+// drainLogs devolve e limpa as mensagens acumuladas
+func (d *Downloader) drainLogs() []string {
+	d.logMu.Lock()
+	defer d.logMu.Unlock()
+	logs := d.logBuffer
+	d.logBuffer = nil
+	return logs
+}
+
+// resetProgress zera os contadores antes de um novo download
+func (d *Downloader) resetProgress() {
+	atomic.StoreInt64(&d.totalTasks, 0)
+	atomic.StoreInt64(&d.completedTasks, 0)
+	atomic.StoreInt64(&d.retryCount, 0)
+}
+
+// updateDownloaderConfig aplica a configuração ao Downloader e grava a opção alterada no arquivo
+func (m *model) updateDownloaderConfig(key string) {
+	m.downloader.applyConfig(m.config)
+	field := findConfigField(key)
+	if err := persistSetting(m.configPath, key, field.get(&m.config)); err != nil {
+		m.logs = append(m.logs, errorStyle.Render(fmt.Sprintf("⚠️ Configuração não foi salva: %v", err)))
+	}
+}
+
+// apiURL monta o endereço de um endpoint a partir da base configurada
+func (d *Downloader) apiURL(path string) string {
+	return d.apiBase + path
+}
+
+// resolveAPIURL aplica a base configurada a links devolvidos pela API (search_uri, next_page).
+// Links relativos são resolvidos contra a base e links absolutos para o Scryfall são
+// redirecionados quando a base aponta para outro servidor.
+func (d *Downloader) resolveAPIURL(link string) string {
+	u, err := url.Parse(link)
+	if err != nil {
+		return link
+	}
+	base, err := url.Parse(d.apiBase + "/")
+	if err != nil {
+		return link
+	}
+
+	if !u.IsAbs() {
+		return base.ResolveReference(&url.URL{Path: strings.TrimPrefix(u.Path, "/"), RawQuery: u.RawQuery}).String()
+	}
+
+	defaultBase, _ := url.Parse(defaultAPIBase)
+	if u.Host == defaultBase.Host && base.Host != defaultBase.Host {
+		u.Scheme = base.Scheme
+		u.Host = base.Host
+		u.Path = strings.TrimRight(base.Path, "/") + u.Path
+		if u.RawPath != "" {
+			u.RawPath = strings.TrimRight(base.EscapedPath(), "/") + u.RawPath
+		}
+		return u.String()
+	}
+	return link
+}
+
+func (d *Downloader) fetchSets(ctx context.Context) ([]Set, error) {
+	if d.useCatalog {
+		c, err := d.openCatalog()
+		if err != nil {
+			return nil, err
+		}
+		return c.sets(), nil
+	}
+
+	resp, err := d.getAPI(ctx, d.apiURL("/sets"))
+	if err != nil {
+		return nil, fmt.Errorf("erro ao buscar sets: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("erro ao buscar sets: HTTP %d", resp.StatusCode)
+	}
+
+	var setData SetData
+	if err := json.NewDecoder(resp.Body).Decode(&setData); err != nil {
+		return nil, fmt.Errorf("erro ao decodificar sets: %w", err)
+	}
+
+	sort.Slice(setData.Data, func(i, j int) bool { return setData.Data[i].ReleasedAt > setData.Data[j].ReleasedAt })
+	return setData.Data, nil
+}
+
+func (d *Downloader) fetchSetCards(ctx context.Context, searchURI string) ([]Card, error) {
+	allCards := []Card{}
+	currentURL := d.resolveAPIURL(searchURI)
+
+	if cards, ok, err := d.catalogSearch(currentURL); ok {
+		return cards, err
+	}
+
+	// Loop para pegar todas as páginas
+	for currentURL != "" {
+		page, err := d.fetchSearchPage(ctx, currentURL)
+		if err != nil {
+			return nil, err
+		}
+		if page == nil {
+			break
+		}
+
+		// Adicionar cartas desta página
+		allCards = append(allCards, page.Data...)
+
+		// Verificar se há mais páginas (o intervalo entre elas fica a cargo do apiLimiter)
+		if page.HasMore && page.NextPage != "" {
+			currentURL = d.resolveAPIURL(page.NextPage)
+		} else {
+			currentURL = ""
+		}
+	}
+
+	return allCards, nil
+}
+
+// searchPage é uma página de resultados de /cards/search
+type searchPage struct {
+	Data       []Card `json:"data"`
+	HasMore    bool   `json:"has_more"`
+	NextPage   string `json:"next_page"`
+	TotalCards int    `json:"total_cards"`
+}
+
+// fetchSearchPage busca uma página de resultados. Retorna nil sem erro quando a busca não
+// encontra nada, já que o Scryfall responde 404 nesse caso.
+func (d *Downloader) fetchSearchPage(ctx context.Context, pageURL string) (*searchPage, error) {
+	resp, err := d.getAPI(ctx, pageURL)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao buscar cartas: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == 404 {
+		return nil, nil
+	}
+	if resp.StatusCode == 400 {
+		// Consulta com sintaxe inválida: o Scryfall explica o motivo em details
+		var apiErr struct {
+			Details string `json:"details"`
+		}
+		if json.NewDecoder(resp.Body).Decode(&apiErr) == nil && apiErr.Details != "" {
+			return nil, fmt.Errorf("busca inválida: %s", apiErr.Details)
+		}
+	}
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("erro ao buscar cartas: HTTP %d", resp.StatusCode)
+	}
+
+	var page searchPage
+	if err := json.NewDecoder(resp.Body).Decode(&page); err != nil {
+		return nil, fmt.Errorf("erro ao decodificar cartas: %w", err)
+	}
+	return &page, nil
+}
+
+func (d *Downloader) fetchCard(ctx context.Context, cardName string) (*Card, error) {
+	cardName = strings.ReplaceAll(strings.ReplaceAll(strings.ReplaceAll(strings.ReplaceAll(cardName, " ", "+"), "/", "+"), ",", "+"), "'", "")
+	return d.fetchCardURL(ctx, d.apiURL("/cards/named?fuzzy="+cardName))
+}
+
+// errCardNotFound é devolvido quando a API responde 404: a carta ou a impressão não existe
+var errCardNotFound = errors.New("carta não encontrada")
+
+// fetchCardURL busca um único objeto de carta na API
+func (d *Downloader) fetchCardURL(ctx context.Context, cardURL string) (*Card, error) {
+	if card, ok, err := d.catalogCard(cardURL); ok {
+		return card, err
+	}
+
+	resp, err := d.getAPI(ctx, cardURL)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao buscar carta: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		var apiErr struct {
+			Type string `json:"type"`
+		}
+		if json.NewDecoder(resp.Body).Decode(&apiErr) == nil && apiErr.Type == "ambiguous" {
+			return nil, errAmbiguousCard
+		}
+		if resp.StatusCode == http.StatusNotFound {
+			return nil, errCardNotFound
+		}
+		return nil, fmt.Errorf("erro ao buscar carta: HTTP %d", resp.StatusCode)
+	}
+
+	var card Card
+	if err := json.NewDecoder(resp.Body).Decode(&card); err != nil {
+		return nil, fmt.Errorf("erro ao decodificar carta: %w", err)
+	}
+	return &card, nil
+}
+
+// sanitizeFileName remove caracteres que não são aceitos em nomes de arquivo
+func sanitizeFileName(fileName string) string {
+	// Limpeza mais robusta de caracteres inválidos
+	fileName = strings.TrimSpace(fileName)
+	invalidChars := []string{":", "?", "\"", "*", "<", ">", "|", "/", "\\"}
+	for _, char := range invalidChars {
+		fileName = strings.ReplaceAll(fileName, char, "")
+	}
+
+	// Remover espaços duplos e caracteres especiais adicionais
+	fileName = strings.ReplaceAll(fileName, "  ", " ")
+	fileName = strings.ReplaceAll(fileName, "'", "")
+	fileName = strings.ReplaceAll(fileName, ",", "")
+	return fileName
+}
+
+// imagePath retorna o caminho final de uma imagem dentro da pasta de download. Tasks gravadas
+// em journals antigos não têm Path e usam o layout original <SET>/<nome>.full.jpg.
+func (d *Downloader) imagePath(task imageTask) string {
+	if task.Path != "" {
+		return filepath.Join(d.downloadDir, filepath.FromSlash(task.Path))
+	}
+	return filepath.Join(d.downloadDir, strings.ToUpper(task.SetCode), sanitizeFileName(task.FileName)+".full.jpg")
+}
+
+// downloadImage baixa para um arquivo temporário, valida o conteúdo e só então o move para o
+// caminho final, para que um download interrompido (ou cancelado) nunca seja tomado como já existente
+func (d *Downloader) downloadImage(ctx context.Context, url, filePath string) error {
+	setDir := filepath.Dir(filePath)
+	if err := os.MkdirAll(setDir, 0755); err != nil {
+		return fmt.Errorf("erro ao criar diretório %s: %w", setDir, err)
+	}
+	fileName := filepath.Base(filePath)
+
+	if _, err := os.Stat(filePath); err == nil {
+		return nil // Já existe
+	}
+
+	resp, err := d.get(ctx, d.imageLimiter, url)
+	if err != nil {
+		return fmt.Errorf("erro ao baixar %s: %w", fileName, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return fmt.Errorf("falha no download %s: HTTP %d", fileName, resp.StatusCode)
+	}
+
+	tmpPath := filePath + partialSuffix
+	file, err := os.Create(tmpPath)
+	if err != nil {
+		return fmt.Errorf("erro ao criar arquivo %s: %w", tmpPath, err)
+	}
+
+	written, err := io.Copy(file, resp.Body)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil && resp.ContentLength >= 0 && written != resp.ContentLength {
+		err = fmt.Errorf("download incompleto %s: %d de %d bytes", fileName, written, resp.ContentLength)
+	}
+	if err == nil {
+		err = validateImageFile(tmpPath)
+	}
+	if err == nil {
+		err = os.Rename(tmpPath, filePath)
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return nil
+}
+
+// imageTask é o download de uma imagem, planejado antes da execução
+type imageTask struct {
+	ID       int    `json:"id"` // posição no journal do job (0 fora de um job)
+	URL      string `json:"url"`
+	FileName string `json:"file"`
+	SetCode  string `json:"set"`
+	Path     string `json:"path,omitempty"` // relativo à pasta de download, separado por "/"
+}
+
+func (d *Downloader) processCard(card Card) []imageTask {
+	var tasks []imageTask
+
+	// Valores do template de nome para a carta inteira ou para uma das faces
+	cardFields := func(name string) imageNameFields {
+		return imageNameFields{
+			Set:             strings.ToUpper(card.Set),
+			CollectorNumber: card.CollectorNumber,
+			Name:            name,
+			Lang:            card.Lang,
+			Rarity:          card.Rarity,
+			Artist:          card.Artist,
+		}
+	}
+	faceFields := func(index int, face CardFace) imageNameFields {
+		fields := cardFields(face.Name)
+		fields.Face = faceLabel(index)
+		if d.pairedFaces {
+			// Frente e verso lado a lado para impressão de proxies: <carta>_front, <carta>_back
+			fields.Name = strings.Split(card.Name, " //")[0] + "_" + fields.Face
+		}
+		if face.Artist != "" {
+			fields.Artist = face.Artist
+		}
+		return fields
+	}
+
+	// Função helper para adicionar task de download. extraType é o tipo pedido quando não é o
+	// primeiro da lista; sem {quality} no template, ele vai para uma subpasta própria.
+	addDownloadTask := func(imageURL string, fields imageNameFields, extraType string) {
+		if imageURL != "" {
+			path := expandNameTemplate(d.nameTemplate, fields)
+			if fields.Lang != "" && fields.Lang != "en" && !strings.Contains(d.nameTemplate, "{lang}") {
+				// Impressões traduzidas ficam numa subpasta do idioma, ao lado das em inglês
+				path = imageTypeFolder(path, fields.Lang)
+			}
+			if extraType != "" && !strings.Contains(d.nameTemplate, "{quality}") {
+				path = imageTypeFolder(path, extraType)
+			}
+			path += imageExt(fields.Quality)
+			tasks = append(tasks, imageTask{URL: imageURL, FileName: fields.Name, SetCode: card.Set, Path: path})
+		}
+	}
+
+	// Função para tentar diferentes qualidades, para cada tipo de imagem configurado
+	tryDownloadWithFallback := func(imageURIs map[string]string, fields imageNameFields) {
+		for i, imageType := range d.imageTypes {
+			qualities := []string{imageType}
+			if !isCropType(imageType) {
+				qualities = append(qualities, "normal", "small", "large") // Recortes não têm substituto
+			}
+			extraType := ""
+			if i > 0 {
+				extraType = imageType
+			}
+
+			for _, quality := range qualities {
+				if imageURL, ok := imageURIs[quality]; ok {
+					fields.Quality = quality
+					addDownloadTask(imageURL, fields, extraType)
+					break // Para no primeiro que encontrar
+				}
+			}
+		}
+	}
+
+	switch card.Layout {
+	case "adventure":
+		// Cartas Adventure (uma face principal)
+		name := strings.Split(card.Name, " //")[0]
+		tryDownloadWithFallback(card.ImageURIs, cardFields(name))
+
+	case "transform", "modal_dfc", "reversible_card", "double_faced_token":
+		// Cartas de dupla face
+		for i, face := range card.CardFaces {
+			if len(face.ImageURIs) > 0 {
+				tryDownloadWithFallback(face.ImageURIs, faceFields(i, face))
+			}
+		}
+
+	case "split", "flip":
+		// Cartas split/flip - geralmente uma imagem só
+		if len(card.ImageURIs) > 0 {
+			tryDownloadWithFallback(card.ImageURIs, cardFields(card.Name))
+		} else {
+			// Fallback para faces individuais se necessário
+			for i, face := range card.CardFaces {
+				if len(face.ImageURIs) > 0 {
+					tryDownloadWithFallback(face.ImageURIs, faceFields(i, face))
+				}
+			}
+		}
+
+	default:
+		// Cartas normais e outros layouts
+		if strings.Contains(card.Name, "//") && len(card.CardFaces) > 0 {
+			// Tem faces separadas
+			for i, face := range card.CardFaces {
+				if len(face.ImageURIs) > 0 {
+					tryDownloadWithFallback(face.ImageURIs, faceFields(i, face))
+				}
+			}
+		} else {
+			// Carta normal
+			if len(card.ImageURIs) > 0 {
+				tryDownloadWithFallback(card.ImageURIs, cardFields(card.Name))
+			}
+		}
+	}
+
+	return tasks
+}
+
+// runTasks executa as tasks com no máximo maxWorkers simultâneos e retorna quantas tiveram sucesso.
+// O resultado de cada task é registrado no journal do job, quando houver; tasks interrompidas pelo
+// cancelamento de ctx não são registradas e continuam pendentes.
+func (d *Downloader) runTasks(ctx context.Context, tasks []imageTask, job *jobJournal) int64 {
+	atomic.StoreInt64(&d.totalTasks, int64(len(tasks)))
+	atomic.StoreInt64(&d.completedTasks, 0)
+
+	semaphore := make(chan struct{}, d.maxWorkers)
+	var wg sync.WaitGroup
+	var successCount int64
+
+	for _, task := range tasks {
+		wg.Add(1)
+		go func(t imageTask) {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+			if ctx.Err() != nil {
+				return
+			}
+
+			err := d.downloadImage(ctx, t.URL, d.imagePath(t))
+			if ctx.Err() != nil {
+				return
+			}
+			if err == nil {
+				atomic.AddInt64(&successCount, 1)
+			}
+			job.markDone(t.ID, err)
+			atomic.AddInt64(&d.completedTasks, 1)
+		}(task)
+	}
+
+	wg.Wait()
+	return successCount
+}
+
+// downloadSets baixa todas as cartas dos sets informados, usando a lista de sets já carregada
+func (d *Downloader) downloadSets(ctx context.Context, sets []Set, setCodes []string) downloadCompleteMsg {
+	job := d.createJob("sets "+strings.Join(setCodes, ","), setCodes)
+	defer d.closeJob(job)
+
+	planner := d.newPathPlanner()
+	allTasks, completed, failed := d.planSets(ctx, job, planner, sets, setCodes)
+	if len(allTasks) == 0 {
+		d.resetProgress()
+		return downloadCompleteMsg{success: false, message: "Nenhuma tarefa para executar", completed: []string{}, failed: setCodes, cancelled: ctx.Err() != nil}
+	}
+
+	successCount := d.runTasks(ctx, allTasks, job)
+
+	successMsg := fmt.Sprintf("Download finalizado: %d imagens processadas", successCount)
+	if len(setCodes) > 1 {
+		successMsg = fmt.Sprintf("Download de %d sets finalizado: %d imagens processadas", len(setCodes), successCount)
+	}
+
+	return downloadCompleteMsg{
+		success:           successCount > 0,
+		message:           successMsg,
+		completed:         completed,
+		failed:            failed,
+		cancelled:         ctx.Err() != nil,
+		variantsKept:      planner.kept,
+		variantsCollapsed: planner.collapsed,
+	}
+}
+
+// planSets busca as cartas de cada set e registra as tasks no journal à medida que cada set é paginado
+// Com ctx cancelado a paginação para e os sets restantes continuam pendentes no journal.
+func (d *Downloader) planSets(ctx context.Context, job *jobJournal, planner *pathPlanner, sets []Set, setCodes []string) (tasks []imageTask, completed, failed []string) {
+	for _, setCode := range setCodes {
+		if ctx.Err() != nil {
+			break
+		}
+		setCode = strings.TrimSpace(strings.ToLower(setCode))
+
+		targetSet := findSet(sets, setCode)
+		if targetSet == nil {
+			d.logf("Set %s não encontrado", strings.ToUpper(setCode))
+			failed = append(failed, setCode)
+			job.planSet(setCode, nil) // Não adianta tentar de novo ao retomar
+			continue
+		}
+
+		cards, err := d.fetchLocalizedCards(ctx, targetSet.SearchURI)
+		if ctx.Err() != nil {
+			break
+		}
+		if err != nil {
+			d.logf("Falha ao buscar cartas de %s: %v", strings.ToUpper(setCode), err)
+			failed = append(failed, setCode)
+			continue
+		}
+		d.logf("Set %s: %d cartas", strings.ToUpper(setCode), len(cards))
+
+		setTasks := d.planCards(planner, cards)
+		if d.includeRelated {
+			related := d.relatedCards(ctx, sets, targetSet, cards)
+			if ctx.Err() != nil {
+				break
+			}
+			d.logf("Set %s: %d tokens, emblemas e art series", strings.ToUpper(setCode), len(related))
+			setTasks = append(setTasks, d.planRelated(planner, targetSet, related)...)
+		}
+		tasks = append(tasks, job.planSet(setCode, setTasks)...)
+		completed = append(completed, setCode)
+	}
+	return tasks, completed, failed
+}
+
+// downloadCard baixa todas as impressões da carta encontrada pela busca fuzzy, ou da carta com
+// exatamente esse nome quando exact é verdadeiro
+func (d *Downloader) downloadCard(ctx context.Context, cardName string, exact bool) (downloadCompleteMsg, error) {
+	var card *Card
+	var err error
+	if exact {
+		card, err = d.fetchCardExact(ctx, cardName)
+	} else {
+		card, err = d.fetchCard(ctx, cardName)
+	}
+	if err != nil {
+		return downloadCompleteMsg{}, err
+	}
+
+	prints, err := d.fetchLocalizedCards(ctx, card.PrintsSearchURI)
+	if err != nil {
+		return downloadCompleteMsg{}, err
+	}
+	d.logf("%s: %d impressões", card.Name, len(prints))
+
+	planner := d.newPathPlanner()
+	allTasks := d.planCards(planner, prints)
+
+	job := d.createJob("card "+card.Name, nil)
+	defer d.closeJob(job)
+	allTasks = job.plan(allTasks)
+
+	successCount := d.runTasks(ctx, allTasks, job)
+
+	successMsg := fmt.Sprintf("✅ %d/%d imagens baixadas para '%s'", successCount, len(allTasks), card.Name)
+	return downloadCompleteMsg{
+		success:           successCount > 0,
+		message:           successMsg,
+		completed:         []string{card.Name},
+		failed:            []string{},
+		cancelled:         ctx.Err() != nil,
+		variantsKept:      planner.kept,
+		variantsCollapsed: planner.collapsed,
+	}, nil
+}
+
+// findSet procura um set pelo código, sem diferenciar maiúsculas
+func findSet(sets []Set, code string) *Set {
+	for i := range sets {
+		if strings.EqualFold(sets[i].Code, code) {
+			return &sets[i]
+		}
+	}
+	return nil
+}
+
+// setCodes retorna os códigos dos sets
+func setCodes(sets []Set) []string {
+	var codes []string
+	for _, set := range sets {
+		codes = append(codes, set.Code)
+	}
+	return codes
+}
+
+// parseAllInput reconhece "ALL" seguido opcionalmente de uma expressão de filtro
+func parseAllInput(input string) (expr string, ok bool) {
+	fields := strings.Fields(input)
+	if len(fields) == 0 || strings.ToUpper(fields[0]) != "ALL" {
+		return "", false
+	}
+	return strings.Join(fields[1:], " "), true
+}
+
+// parseSetCodes separa uma lista de códigos por vírgula, ignorando entradas vazias
+func parseSetCodes(input string) []string {
+	var codes []string
+	for _, code := range strings.Split(input, ",") {
+		if clean := strings.TrimSpace(code); clean != "" {
+			codes = append(codes, clean)
+		}
+	}
+	return codes
+}
+
+// filterSets retorna os sets cujo código, nome ou tipo contém o filtro
+func filterSets(sets []Set, filter string) []Set {
+	filter = strings.ToLower(filter)
+	var result []Set
+	for _, set := range sets {
+		if filter == "" || strings.Contains(strings.ToLower(set.Code), filter) ||
+			strings.Contains(strings.ToLower(set.Name), filter) ||
+			strings.Contains(strings.ToLower(set.SetType), filter) {
+			result = append(result, set)
+		}
+	}
+	return result
+}
+
+func initialModel(cfg Config, configPath string) model {
+	s := spinner.New()
+	s.Spinner = spinner.Dot
+	s.Style = lipgloss.NewStyle().Foreground(lipgloss.Color("205"))
+
+	ti := textinput.New()
+	ti.Placeholder = "Digite aqui..."
+	ti.CharLimit = 200
+	ti.Width = 60
+
+	si := textinput.New()
+	si.Placeholder = "Buscar sets..."
+	si.CharLimit = 50
+	si.Width = 40
+
+	prog := progress.New(progress.WithDefaultGradient())
+
+	setList := list.New([]list.Item{}, list.NewDefaultDelegate(), 70, 20)
+	setList.Title = "Sets Disponíveis"
+	setList.SetShowStatusBar(true)
+	setList.SetFilteringEnabled(true)
+
+	return model{
+		state:       menuState,
+		spinner:     s,
+		textInput:   ti,
+		searchInput: si,
+		progress:    prog,
+		setList:     setList,
+		currentMenu: 0,
+		menuOptions: []string{"🎴 Download por Set", "🃏 Download por Carta", "🔍 Download por Busca", "📜 Importar Decklist", "♻️ Retomar Downloads", "📋 Listar/Buscar Sets", "⚙️ Configurações", "🚪 Sair"},
+		config:      cfg,
+		configPath:  configPath,
+		logs:        []string{},
+		downloader:  NewDownloader(cfg),
+	}
+}
+
+func (m model) Init() tea.Cmd { return m.spinner.Tick }
+
+func (m model) tickProgress() tea.Cmd {
+	return tea.Tick(time.Millisecond*100, func(t time.Time) tea.Msg {
+		current := atomic.LoadInt64(&m.downloader.completedTasks)
+		total := atomic.LoadInt64(&m.downloader.totalTasks)
+		return progressUpdateMsg{current: int(current), total: int(total), message: fmt.Sprintf("Baixando... %d/%d", current, total), logs: m.downloader.drainLogs()}
+	})
+}
+
+func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch m.state {
+		case menuState:
+			switch msg.String() {
+			case "up", "k":
+				if m.currentMenu > 0 {
+					m.currentMenu--
+				}
+			case "down", "j":
+				if m.currentMenu < len(m.menuOptions)-1 {
+					m.currentMenu++
+				}
+			case "enter":
+				switch m.currentMenu {
+				case menuSetDownload:
+					m.state = setDownloadState
+					m.logs = []string{}
+					m.pendingCodes = nil
+					m.textInput.SetValue("")
+					m.textInput.Placeholder = "Códigos dos sets separados por vírgula (ex: dom,war,m21) ou 'ALL' para todos"
+					m.textInput.Focus()
+					if len(m.sets) == 0 {
+						return m, m.fetchSetsCmd()
+					}
+				case menuCardDownload:
+					m.state = cardDownloadState
+					m.clearSuggestions()
+					m.textInput.SetValue("")
+					m.textInput.Placeholder = "Nome da carta (ex: Lightning Bolt)"
+					m.textInput.Focus()
+				case menuSearchDownload:
+					m.state = searchDownloadState
+					m.logs = []string{}
+					m.searchQuery = ""
+					m.textInput.SetValue("")
+					m.textInput.Placeholder = "Busca do Scryfall (ex: t:dragon r:mythic year>=2021)"
+					m.textInput.Focus()
+				case menuDeckImport:
+					m.state = deckImportState
+					m.textInput.SetValue("")
+					m.textInput.Placeholder = "Caminho do arquivo (ex: C:\\Decks\\burn.txt)"
+					m.textInput.Focus()
+				case menuResumeJobs:
+					m.state = jobsState
+					m.jobs = nil
+					m.jobCursor = 0
+					return m, m.loadJobsCmd()
+				case menuSetSearch:
+					m.state = setSearchState
+					m.searchInput.SetValue("")
+					m.searchInput.Focus()
+					if len(m.sets) == 0 {
+						return m, m.fetchSetsCmd()
+					} else {
+						m.updateSetList("")
+					}
+				case menuConfig:
+					m.state = configState
+					m.currentMenu = 0
+				case menuQuit:
+					return m, tea.Quit
+				}
+			case "q", "ctrl+c":
+				return m, tea.Quit
+			}
+
+		case setSearchState:
+			switch msg.String() {
+			case "esc":
+				m.state = menuState
+				m.searchInput.Blur()
+			case "tab":
+				// Marca ou desmarca o set sob o cursor. Não usa espaço, que faz parte de nomes como
+				// "Modern Horizons" digitados na busca
+				if item, ok := m.setList.SelectedItem().(setItem); ok {
+					if m.selectedSets == nil {
+						m.selectedSets = make(map[string]bool)
+					}
+					item.selected = !m.selectedSets[item.set.Code]
+					if item.selected {
+						m.selectedSets[item.set.Code] = true
+					} else {
+						delete(m.selectedSets, item.set.Code)
+					}
+					return m, m.setList.SetItem(m.setList.Index(), item)
+				}
+			case "enter":
+				if len(m.selectedSets) > 0 {
+					var codes []string
+					for _, set := range m.sets {
+						if m.selectedSets[set.Code] {
+							codes = append(codes, set.Code)
+						}
+					}
+					m.selectedSets = nil
+					m.searchInput.Blur()
+					ctx := m.startDownload()
+					m.logs = []string{fmt.Sprintf("🚀 Iniciando download de %d sets: %s", len(codes), strings.Join(codes, ", "))}
+					return m, tea.Batch(m.spinner.Tick, m.tickProgress(), m.downloadMultipleSetsCmd(ctx, codes))
+				}
+			case "up", "down", "pgup", "pgdown":
+				var listCmd tea.Cmd
+				m.setList, listCmd = m.setList.Update(msg)
+				return m, listCmd
+			default:
+				var cmd tea.Cmd
+				m.searchInput, cmd = m.searchInput.Update(msg)
+				m.updateSetList(m.searchInput.Value())
+				return m, cmd
+			}
+
+		case setDownloadState:
+			switch msg.String() {
+			case "enter":
+				if m.pendingCodes != nil {
+					// Prévia confirmada
+					codes := m.pendingCodes
+					m.pendingCodes = nil
+					ctx := m.startDownload()
+					m.logs = []string{fmt.Sprintf("🚀 Iniciando download de TODOS os sets (%d sets)", len(codes))}
+					return m, tea.Batch(m.spinner.Tick, m.tickProgress(), m.downloadMultipleSetsCmd(ctx, codes))
+				}
+
+				input := strings.TrimSpace(m.textInput.Value())
+				if expr, ok := parseAllInput(input); ok {
+					if expr == "" {
+						expr = m.config.AllFilter
+					}
+					filter, err := parseSetFilter(expr)
+					if err != nil {
+						m.logs = []string{errorStyle.Render(fmt.Sprintf("⚠️ %v", err))}
+						return m, nil
+					}
+					if len(m.sets) == 0 {
+						m.logs = []string{errorStyle.Render("⚠️ Lista de sets ainda não carregada")}
+						return m, nil
+					}
+					matched, cards := filter.apply(m.sets)
+					if len(matched) == 0 {
+						m.logs = []string{errorStyle.Render(fmt.Sprintf("⚠️ Nenhum set corresponde ao filtro %q", expr))}
+						return m, nil
+					}
+					m.pendingCodes = setCodes(matched)
+					m.pendingCards = cards
+					m.pendingExpr = expr
+					m.logs = []string{}
+				} else if input != "" {
+					ctx := m.startDownload()
+					cleanCodes := parseSetCodes(input)
+					m.logs = []string{fmt.Sprintf("🚀 Iniciando download de %d sets: %s", len(cleanCodes), strings.Join(cleanCodes, ", "))}
+					return m, tea.Batch(m.spinner.Tick, m.tickProgress(), m.downloadMultipleSetsCmd(ctx, cleanCodes))
+				}
+			case "esc":
+				if m.pendingCodes != nil {
+					m.pendingCodes = nil
+					return m, nil
+				}
+				m.state = menuState
+				m.textInput.Blur()
+			default:
+				var cmd tea.Cmd
+				m.textInput, cmd = m.textInput.Update(msg)
+				return m, cmd
+			}
+
+		case cardDownloadState:
+			switch msg.String() {
+			case "enter":
+				// Uma sugestão escolhida é um nome exato; o texto digitado passa pela busca fuzzy
+				cardName, exact := strings.TrimSpace(m.textInput.Value()), false
+				if m.suggestionCursor >= 0 && m.suggestionCursor < len(m.suggestions) {
+					cardName, exact = m.suggestions[m.suggestionCursor], true
+				}
+				if cardName != "" {
+					m.clearSuggestions()
+					ctx := m.startDownload()
+					m.logs = []string{}
+					return m, tea.Batch(m.spinner.Tick, m.tickProgress(), m.downloadCardCmd(ctx, cardName, exact))
+				}
+			case "up":
+				if m.suggestionCursor >= 0 {
+					m.suggestionCursor--
+				}
+			case "down":
+				if m.suggestionCursor < len(m.suggestions)-1 {
+					m.suggestionCursor++
+				}
+			case "tab":
+				// Completa o campo com a sugestão destacada (ou a primeira)
+				if len(m.suggestions) > 0 {
+					m.textInput.SetValue(m.suggestions[max(m.suggestionCursor, 0)])
+					m.textInput.CursorEnd()
+					m.clearSuggestions()
+				}
+			case "esc":
+				m.state = menuState
+				m.textInput.Blur()
+			default:
+				before := m.textInput.Value()
+				var cmd tea.Cmd
+				m.textInput, cmd = m.textInput.Update(msg)
+				if value := strings.TrimSpace(m.textInput.Value()); m.textInput.Value() != before {
+					m.clearSuggestions()
+					if len([]rune(value)) >= minAutocompleteLen {
+						return m, tea.Batch(cmd, autocompleteTick(value))
+					}
+				}
+				return m, cmd
+			}
+
+		case searchDownloadState:
+			switch msg.String() {
+			case "enter":
+				query := strings.TrimSpace(m.textInput.Value())
+				if m.searchQuery != "" && m.searchQuery == query && m.searchHits > 0 {
+					// Prévia confirmada
+					m.searchQuery = ""
+					ctx := m.startDownload()
+					m.logs = []string{fmt.Sprintf("🚀 Iniciando download da busca: %s (%d cartas)", query, m.searchHits)}
+					return m, tea.Batch(m.spinner.Tick, m.tickProgress(), m.downloadSearchCmd(ctx, query))
+				}
+				if query != "" {
+					m.logs = []string{"🔎 Buscando..."}
+					m.searchQuery = ""
+					return m, m.countSearchCmd(query)
+				}
+			case "esc":
+				m.state = menuState
+				m.textInput.Blur()
+			default:
+				var cmd tea.Cmd
+				m.textInput, cmd = m.textInput.Update(msg)
+				if strings.TrimSpace(m.textInput.Value()) != m.searchQuery {
+					m.searchQuery = "" // Busca editada: a prévia não vale mais
+				}
+				return m, cmd
+			}
+
+		case deckImportState:
+			switch msg.String() {
+			case "enter":
+				if path := strings.TrimSpace(m.textInput.Value()); path != "" {
+					ctx := m.startDownload()
+					m.logs = []string{fmt.Sprintf("🚀 Importando decklist: %s", path)}
+					return m, tea.Batch(m.spinner.Tick, m.tickProgress(), m.downloadDeckCmd(ctx, path))
+				}
+			case "esc":
+				m.state = menuState
+				m.textInput.Blur()
+			default:
+				var cmd tea.Cmd
+				m.textInput, cmd = m.textInput.Update(msg)
+				return m, cmd
+			}
+
+		case jobsState:
+			switch msg.String() {
+			case "up", "k":
+				if m.jobCursor > 0 {
+					m.jobCursor--
+				}
+			case "down", "j":
+				if m.jobCursor < len(m.jobs)-1 {
+					m.jobCursor++
+				}
+			case "enter":
+				if m.jobCursor < len(m.jobs) {
+					job := m.jobs[m.jobCursor]
+					ctx := m.startDownload()
+					m.logs = []string{fmt.Sprintf("🚀 Retomando job %s: %s", job.ID, job.Description)}
+					return m, tea.Batch(m.spinner.Tick, m.tickProgress(), m.resumeJobCmd(ctx, job))
+				}
+			case "d", "delete":
+				if m.jobCursor < len(m.jobs) {
+					if err := m.jobs[m.jobCursor].remove(); err != nil {
+						m.logs = append(m.logs, errorStyle.Render(fmt.Sprintf("❌ Erro ao descartar job: %v", err)))
+					}
+					return m, m.loadJobsCmd()
+				}
+			case "esc", "q":
+				m.state = menuState
+			}
+
+		case setListState:
+			switch {
+			case m.confirmCancel:
+				switch msg.String() {
+				case "s", "y":
+					m.confirmCancel = false
+					m.cancel()
+					m.logs = append(m.logs, warningStyle.Render("🛑 Cancelando download..."))
+				case "n", "esc":
+					m.confirmCancel = false
+				}
+			case m.cancel != nil:
+				switch msg.String() {
+				case "esc", "q":
+					m.confirmCancel = true
+				case "p":
+					paused := !m.downloader.pause.isPaused()
+					m.downloader.pause.set(paused)
+					if paused {
+						m.logs = append(m.logs, warningStyle.Render("⏸️ Download pausado"))
+					} else {
+						m.logs = append(m.logs, warningStyle.Render("▶️ Download retomado"))
+					}
+				}
+			case msg.String() == "esc" || msg.String() == "q":
+				m.state = menuState
+			}
+
+		case configState:
+			switch msg.String() {
+			case "esc":
+				if m.textInput.Focused() {
+					m.textInput.Blur()
+				} else {
+					m.state = menuState
+				}
+			case "up", "k":
+				if !m.textInput.Focused() && m.currentMenu > 0 {
+					m.currentMenu--
+				}
+			case "down", "j":
+				if !m.textInput.Focused() && m.currentMenu < configBack {
+					m.currentMenu++
+				}
+			case "enter":
+				if m.textInput.Focused() {
+					// Processar o valor inserido
+					value := strings.TrimSpace(m.textInput.Value())
+					switch m.currentMenu {
+					case configDownloadDir:
+						if value != "" {
+							if err := os.MkdirAll(value, 0755); err != nil {
+								m.logs = append(m.logs, errorStyle.Render(fmt.Sprintf("❌ Erro ao acessar pasta: %v", err)))
+							} else {
+								m.config.DownloadDir = value
+								m.updateDownloaderConfig("download_dir")
+								m.logs = append(m.logs, successStyle.Render(fmt.Sprintf("✅ Pasta alterada para: %s", value)))
+							}
+						}
+					case configWorkers:
+						if workers, err := strconv.Atoi(value); err == nil && workers > 0 && workers <= 50 {
+							m.config.MaxWorkers = workers
+							m.updateDownloaderConfig("max_workers")
+							m.logs = append(m.logs, successStyle.Render(fmt.Sprintf("✅ Workers alterados para: %d", workers)))
+						} else {
+							m.logs = append(m.logs, errorStyle.Render("⚠️ Número de workers deve ser entre 1 e 50"))
+						}
+					case configRetries:
+						if retries, err := strconv.Atoi(value); err == nil && retries >= 0 && retries <= 10 {
+							m.config.MaxRetries = retries
+							m.updateDownloaderConfig("max_retries")
+							m.logs = append(m.logs, successStyle.Render(fmt.Sprintf("✅ Retentativas alteradas para: %d", retries)))
+						} else {
+							m.logs = append(m.logs, errorStyle.Render("⚠️ Número de retentativas deve ser entre 0 e 10"))
+						}
+					case configRetryDelay:
+						if delay, err := strconv.Atoi(value); err == nil && delay >= 100 && delay <= 60000 {
+							m.config.RetryDelayMs = delay
+							m.updateDownloaderConfig("retry_delay_ms")
+							m.logs = append(m.logs, successStyle.Render(fmt.Sprintf("✅ Espera inicial alterada para: %dms", delay)))
+						} else {
+							m.logs = append(m.logs, errorStyle.Render("⚠️ Espera inicial deve ser entre 100 e 60000 ms"))
+						}
+					case configQuality:
+						cfg := m.config
+						cfg.Quality = strings.Join(parseImageTypes(value), ",")
+						if err := validateImageTypes(cfg.Quality); err != nil {
+							m.logs = append(m.logs, errorStyle.Render(fmt.Sprintf("⚠️ %v", err)))
+						} else {
+							m.config.Quality = cfg.Quality
+							m.updateDownloaderConfig("quality")
+							m.logs = append(m.logs, successStyle.Render(fmt.Sprintf("✅ Qualidade alterada para: %s", m.config.Quality)))
+						}
+					case configNameTemplate:
+						if value == "" {
+							value = defaultNameTemplate
+						}
+						if err := validateNameTemplate(value); err != nil {
+							m.logs = append(m.logs, errorStyle.Render(fmt.Sprintf("⚠️ %v", err)))
+						} else {
+							m.config.NameTemplate = value
+							m.updateDownloaderConfig("name_template")
+							m.logs = append(m.logs, successStyle.Render(fmt.Sprintf("✅ Nome dos arquivos alterado para: %s", value)))
+						}
+					case configLang:
+						lang := strings.ToLower(value)
+						if lang == "" {
+							lang = "en"
+						}
+						if containsString(languageOptions, lang) {
+							m.config.Lang = lang
+							m.updateDownloaderConfig("lang")
+							m.logs = append(m.logs, successStyle.Render(fmt.Sprintf("✅ Idioma alterado para: %s", lang)))
+						} else {
+							m.logs = append(m.logs, errorStyle.Render(fmt.Sprintf("⚠️ Idioma inválido (use %s)", strings.Join(languageOptions, ", "))))
+						}
+					case configAPIRate, configImageRate:
+						if rate, err := strconv.ParseFloat(value, 64); err == nil && rate >= 0 && rate <= 100 {
+							if m.currentMenu == configAPIRate {
+								m.config.APIRate = rate
+								m.updateDownloaderConfig("api_rate")
+							} else {
+								m.config.ImageRate = rate
+								m.updateDownloaderConfig("image_rate")
+							}
+							m.logs = append(m.logs, successStyle.Render(fmt.Sprintf("✅ Limite alterado para: %s req/s", formatRate(rate))))
+						} else {
+							m.logs = append(m.logs, errorStyle.Render("⚠️ Limite deve ser entre 0 (sem limite) e 100 req/s"))
+						}
+					case configAPIBase:
+						if value == "" {
+							value = defaultAPIBase
+						}
+						if err := validateAPIBase(value); err != nil {
+							m.logs = append(m.logs, errorStyle.Render(fmt.Sprintf("⚠️ %v", err)))
+						} else {
+							m.config.APIBase = value
+							m.sets = nil // A lista de sets veio do servidor anterior
+							m.updateDownloaderConfig("api_url")
+							m.logs = append(m.logs, successStyle.Render(fmt.Sprintf("✅ API alterada para: %s", value)))
+						}
+					}
+					m.textInput.Blur()
+					// Limitar logs
+					if len(m.logs) > 10 {
+						m.logs = m.logs[len(m.logs)-10:]
+					}
+				} else {
+					// Não está editando, então iniciar edição
+					switch m.currentMenu {
+					case configDownloadDir:
+						m.textInput.SetValue(m.config.DownloadDir)
+						m.textInput.Placeholder = "Caminho da pasta (ex: C:\\MinhasCartas)"
+						m.textInput.Focus()
+					case configQuality:
+						m.textInput.SetValue(m.config.Quality)
+						m.textInput.Placeholder = "Tipos de imagem separados por vírgula (ex: large,art_crop)"
+						m.textInput.Focus()
+					case configPreset:
+						// Alterna entre nenhum preset e cada um dos presets
+						options := append([]string{""}, exportPresetNames()...)
+						nextIndex := 0
+						for i, name := range options {
+							if name == m.config.Preset {
+								nextIndex = (i + 1) % len(options)
+								break
+							}
+						}
+						m.config.Preset = options[nextIndex]
+						m.updateDownloaderConfig("preset")
+						m.logs = append(m.logs, successStyle.Render(fmt.Sprintf("✅ Preset alterado para: %s", presetLabel(m.config.Preset))))
+						if len(m.logs) > 10 {
+							m.logs = m.logs[len(m.logs)-10:]
+						}
+					case configVariants:
+						if m.config.Variants == "first" {
+							m.config.Variants = "all"
+						} else {
+							m.config.Variants = "first"
+						}
+						m.updateDownloaderConfig("variants")
+						m.logs = append(m.logs, successStyle.Render(fmt.Sprintf("✅ Variantes: %s", variantsLabel(m.config.Variants))))
+						if len(m.logs) > 10 {
+							m.logs = m.logs[len(m.logs)-10:]
+						}
+					case configFaceNames:
+						if m.config.FaceNames == "paired" {
+							m.config.FaceNames = "face"
+						} else {
+							m.config.FaceNames = "paired"
+						}
+						m.updateDownloaderConfig("face_names")
+						m.logs = append(m.logs, successStyle.Render(fmt.Sprintf("✅ Faces: %s", faceNamesLabel(m.config.FaceNames))))
+						if len(m.logs) > 10 {
+							m.logs = m.logs[len(m.logs)-10:]
+						}
+					case configRelated:
+						m.config.IncludeRelated = !m.config.IncludeRelated
+						m.updateDownloaderConfig("related")
+						m.logs = append(m.logs, successStyle.Render(fmt.Sprintf("✅ Tokens e art series: %s", relatedLabel(m.config))))
+						if len(m.logs) > 10 {
+							m.logs = m.logs[len(m.logs)-10:]
+						}
+					case configLang:
+						m.textInput.SetValue(m.config.Lang)
+						m.textInput.Placeholder = "Código do idioma (ex: pt, ja, de; vazio para en)"
+						m.textInput.Focus()
+					case configLangFallback:
+						m.config.LangFallback = !m.config.LangFallback
+						m.updateDownloaderConfig("lang_fallback")
+						m.logs = append(m.logs, successStyle.Render(fmt.Sprintf("✅ Sem tradução: %s", langFallbackLabel(m.config.LangFallback))))
+						if len(m.logs) > 10 {
+							m.logs = m.logs[len(m.logs)-10:]
+						}
+					case configUseCatalog:
+						m.config.UseCatalog = !m.config.UseCatalog
+						m.sets = nil // A lista de sets veio da outra fonte
+						m.updateDownloaderConfig("use_catalog")
+						m.logs = append(m.logs, successStyle.Render(fmt.Sprintf("✅ Catálogo offline: %s", yesNo(m.config.UseCatalog))))
+						if len(m.logs) > 10 {
+							m.logs = m.logs[len(m.logs)-10:]
+						}
+					case configCardBack:
+						m.config.CardBack = !m.config.CardBack
+						m.updateDownloaderConfig("card_back")
+						m.logs = append(m.logs, successStyle.Render(fmt.Sprintf("✅ Verso padrão: %s", yesNo(m.config.CardBack))))
+						if len(m.logs) > 10 {
+							m.logs = m.logs[len(m.logs)-10:]
+						}
+					case configNameTemplate:
+						m.textInput.SetValue(m.config.NameTemplate)
+						m.textInput.Placeholder = "Template do nome (vazio para " + defaultNameTemplate + ")"
+						m.textInput.Focus()
+					case configWorkers:
+						m.textInput.SetValue(strconv.Itoa(m.config.MaxWorkers))
+						m.textInput.Placeholder = "Número de workers (1-50)"
+						m.textInput.Focus()
+					case configAPIBase:
+						m.textInput.SetValue(m.config.APIBase)
+						m.textInput.Placeholder = "Endereço da API (vazio para " + defaultAPIBase + ")"
+						m.textInput.Focus()
+					case configRetries:
+						m.textInput.SetValue(strconv.Itoa(m.config.MaxRetries))
+						m.textInput.Placeholder = "Retentativas por requisição (0-10)"
+						m.textInput.Focus()
+					case configRetryDelay:
+						m.textInput.SetValue(strconv.Itoa(m.config.RetryDelayMs))
+						m.textInput.Placeholder = "Espera antes da primeira retentativa, em ms (100-60000)"
+						m.textInput.Focus()
+					case configAPIRate:
+						m.textInput.SetValue(formatRate(m.config.APIRate))
+						m.textInput.Placeholder = "Requisições por segundo à API (0 = sem limite)"
+						m.textInput.Focus()
+					case configImageRate:
+						m.textInput.SetValue(formatRate(m.config.ImageRate))
+						m.textInput.Placeholder = "Downloads de imagem por segundo (0 = sem limite)"
+						m.textInput.Focus()
+					case configBack:
+						m.state = menuState
+					}
+				}
+			default:
+				if m.textInput.Focused() {
+					var cmd tea.Cmd
+					m.textInput, cmd = m.textInput.Update(msg)
+					return m, cmd
+				}
+			}
+		}
+
+	case setListMsg:
+		m.sets = []Set(msg)
+		if m.state == setSearchState {
+			m.updateSetList(m.searchInput.Value())
+		}
+
+	case autocompleteTickMsg:
+		if m.state == cardDownloadState && strings.TrimSpace(m.textInput.Value()) == msg.query {
+			return m, m.autocompleteCmd(msg.query)
+		}
+
+	case suggestionsMsg:
+		// A lista de desambiguação tem prioridade sobre sugestões que chegaram atrasadas
+		if m.state == cardDownloadState && m.ambiguousName == "" && strings.TrimSpace(m.textInput.Value()) == msg.query {
+			m.suggestions = msg.names[:min(len(msg.names), maxSuggestions)]
+			m.suggestionCursor = -1
+		}
+
+	case ambiguousCardMsg:
+		// Volta para o campo do nome com as opções para o usuário escolher
+		m.finishDownload()
+		m.state = cardDownloadState
+		m.logs = []string{}
+		m.textInput.SetValue(msg.name)
+		m.textInput.CursorEnd()
+		m.textInput.Focus()
+		m.suggestions = msg.names[:min(len(msg.names), maxSuggestions)]
+		m.suggestionCursor = 0
+		m.ambiguousName = msg.name
+
+	case searchCountMsg:
+		if m.state == searchDownloadState && strings.TrimSpace(m.textInput.Value()) == msg.query {
+			m.logs = []string{}
+			m.searchQuery = msg.query
+			m.searchHits = msg.total
+		}
+
+	case jobListMsg:
+		m.jobs = []*jobJournal(msg)
+		if m.jobCursor >= len(m.jobs) && len(m.jobs) > 0 {
+			m.jobCursor = len(m.jobs) - 1
+		}
+
+	case progressUpdateMsg:
+		if len(msg.logs) > 0 {
+			m.logs = append(m.logs, msg.logs...)
+			if len(m.logs) > 50 {
+				m.logs = m.logs[len(m.logs)-50:]
+			}
+		}
+		if msg.total > 0 {
+			progress := float64(msg.current) / float64(msg.total)
+			cmd := m.progress.SetPercent(progress)
+			return m, tea.Batch(cmd, m.tickProgress())
+		}
+		return m, m.tickProgress()
+
+	case downloadCompleteMsg:
+		m.finishDownload()
+		if msg.cancelled {
+			m.logs = append(m.logs, "", warningStyle.Render("🛑 DOWNLOAD CANCELADO"), msg.message, "Use ♻️ Retomar Downloads para continuar de onde parou")
+		} else {
+			m.logs = append(m.logs, "", successStyle.Render("🎉 DOWNLOAD COMPLETO!"), msg.message)
+		}
+		if len(msg.completed) > 0 {
+			m.logs = append(m.logs, successStyle.Render(fmt.Sprintf("✅ Sets baixados com sucesso (%d):", len(msg.completed))))
+			for _, code := range msg.completed {
+				m.logs = append(m.logs, successStyle.Render(fmt.Sprintf("  ✓ %s", strings.ToUpper(code))))
+			}
+		}
+		if len(msg.failed) > 0 {
+			m.logs = append(m.logs, errorStyle.Render(fmt.Sprintf("❌ Sets com falha (%d):", len(msg.failed))))
+			for _, code := range msg.failed {
+				m.logs = append(m.logs, errorStyle.Render(fmt.Sprintf("  ✗ %s", strings.ToUpper(code))))
+			}
+		}
+		if msg.variantsKept > 0 || msg.variantsCollapsed > 0 {
+			m.logs = append(m.logs, infoStyle.Render(fmt.Sprintf("🎨 Variantes: %d mantidas, %d descartadas", msg.variantsKept, msg.variantsCollapsed)))
+		}
+		if len(msg.skipped) > 0 {
+			m.logs = append(m.logs, warningStyle.Render(fmt.Sprintf("⚠️ Linhas não reconhecidas (%d):", len(msg.skipped))))
+			for _, line := range msg.skipped {
+				m.logs = append(m.logs, warningStyle.Render(fmt.Sprintf("  ? %s", line)))
+			}
+		}
+		if len(m.logs) > 20 {
+			m.logs = m.logs[len(m.logs)-20:]
+		}
+
+	case errorMsg:
+		if m.state == setListState {
+			m.finishDownload()
+		}
+		m.logs = append(m.logs, errorStyle.Render(fmt.Sprintf("❌ Erro: %v", msg.err)))
+		if len(m.logs) > 15 {
+			m.logs = m.logs[1:]
+		}
+
+	default:
+		var cmd tea.Cmd
+		m.spinner, cmd = m.spinner.Update(msg)
+		return m, cmd
+	}
+
+	return m, nil
+}
+
+// presetLabel descreve o preset para a tela de configurações
+func presetLabel(name string) string {
+	if preset := findExportPreset(name); preset != nil {
+		return fmt.Sprintf("%s (%s)", preset.label, preset.template)
+	}
+	return "nenhum (usa o template)"
+}
+
+func variantsLabel(variants string) string {
+	if variants == "first" {
+		return "só a primeira arte"
+	}
+	return "todas as artes"
+}
+
+func faceNamesLabel(faceNames string) string {
+	if faceNames == "paired" {
+		return "<carta>_front e <carta>_back"
+	}
+	return "nome de cada face"
+}
+
+func relatedLabel(cfg Config) string {
+	switch {
+	case !cfg.IncludeRelated:
+		return "não"
+	case cfg.RelatedFolder == "":
+		return "sim, junto com o set"
+	}
+	return fmt.Sprintf("sim, na subpasta %s", cfg.RelatedFolder)
+}
+
+// langFallbackLabel descreve o que acontece com cartas sem impressão no idioma escolhido
+func langFallbackLabel(fallback bool) string {
+	if fallback {
+		return "baixar em inglês"
+	}
+	return "pular"
+}
+
+func yesNo(value bool) string {
+	if value {
+		return "sim"
+	}
+	return "não"
+}
+
+// startDownload abre a tela de progresso e cria o contexto usado para cancelar o download
+func (m *model) startDownload() context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+	m.cancel = cancel
+	m.confirmCancel = false
+	m.state = setListState
+	m.downloader.resetProgress()
+	m.downloader.pause.set(false)
+	return ctx
+}
+
+// finishDownload libera o contexto do download que terminou
+func (m *model) finishDownload() {
+	if m.cancel != nil {
+		m.cancel()
+		m.cancel = nil
+	}
+	m.confirmCancel = false
+	m.downloader.pause.set(false)
+}
+
+func (m *model) updateSetList(filter string) {
+	items := []list.Item{}
+	for _, set := range filterSets(m.sets, filter) {
+		items = append(items, setItem{set: set, selected: m.selectedSets[set.Code]})
+	}
+	m.setList.SetItems(items)
+}
+
+func (m model) View() string {
+	switch m.state {
+	case menuState:
+		return m.renderMenu()
+	case setSearchState:
+		return m.renderSetSearch()
+	case setDownloadState:
+		return m.renderSetDownloadInput()
+	case cardDownloadState:
+		return m.renderCardInput()
+	case searchDownloadState:
+		return m.renderSearchInput()
+	case deckImportState:
+		return m.renderDeckInput()
+	case jobsState:
+		return m.renderJobs()
+	case setListState:
+		return m.renderDownload()
+	case configState:
+		return m.renderConfig()
+	default:
+		return "Estado desconhecido"
+	}
+}
+
+func (m model) renderMenu() string {
+	s := titleStyle.Render("🎴 MTG Card Downloader") + "\n\n"
+	for i, option := range m.menuOptions {
+		cursor := " "
+		if m.currentMenu == i {
+			cursor = selectedStyle.Render("▶")
+		}
+		s += fmt.Sprintf("%s %s\n", cursor, option)
+	}
+	s += "\n" + helpStyle.Render("↑/↓: navegar • enter: selecionar • q: sair")
+	return s
+}
+
+func (m model) renderSetSearch() string {
+	s := titleStyle.Render("📋 Listar/Buscar Sets") + "\n\n"
+	if len(m.sets) == 0 {
+		s += m.spinner.View() + " Carregando sets...\n\n"
+	} else {
+		s += "🔍 Buscar: " + m.searchInput.View() + "\n\n"
+		s += m.setList.View()
+		s += "\n" + infoStyle.Render(fmt.Sprintf("Total: %d sets | Mostrando: %d", len(m.sets), len(m.setList.Items())))
+		if len(m.selectedSets) > 0 {
+			cards := 0
+			for _, set := range m.sets {
+				if m.selectedSets[set.Code] {
+					cards += set.CardCount
+				}
+			}
+			s += "\n" + successStyle.Render(fmt.Sprintf("Selecionados: %d sets | %d cartas", len(m.selectedSets), cards))
+		}
+	}
+	s += "\n" + helpStyle.Render("digite para buscar • ↑/↓: navegar • tab: marcar • enter: baixar marcados • esc: voltar")
+	return s
+}
+
+func (m model) renderSetDownloadInput() string {
+	s := titleStyle.Render("🎴 Download por Set(s)") + "\n\n"
+	s += "Digite os códigos dos sets:\n" + m.textInput.View() + "\n\n"
+	s += infoStyle.Render("💡 Dicas:") + "\n"
+	s += "  • Sets únicos: dom\n  • Múltiplos sets: dom,war,m21\n  • Todos os sets: ALL\n"
+	s += "  • Todos com filtro: ALL type:expansion,core -type:promo from:2015 to:2020 min:100 digital\n\n"
+
+	if m.pendingCodes != nil {
+		filter := m.pendingExpr
+		if filter == "" {
+			filter = "nenhum (todos os sets não digitais)"
+		}
+		s += infoStyle.Render("🔎 Prévia do ALL") + "\n"
+		s += fmt.Sprintf("  Filtro: %s\n", filter)
+		s += fmt.Sprintf("  %d sets, %d cartas\n\n", len(m.pendingCodes), m.pendingCards)
+		s += warningStyle.Render("⚠️ Confirmar o download de todos esses sets?") + "\n\n"
+		s += helpStyle.Render("enter: iniciar download • esc: voltar")
+		return s
+	}
+	for _, log := range m.logs {
+		s += log + "\n"
+	}
+
+	if len(m.sets) > 0 {
+		s += infoStyle.Render("🆕 Sets mais recentes:") + "\n"
+		for i, set := range m.sets[:min(5, len(m.sets))] {
+			digital := ""
+			if set.Digital {
+				digital = " 💻"
+			}
+			s += fmt.Sprintf("  %s - %s%s (%d cartas)\n", strings.ToUpper(set.Code), set.Name, digital, set.CardCount)
+			if i >= 4 {
+				break
+			}
+		}
+		s += "\n"
+	}
+
+	s += warningStyle.Render("⚠️ 'ALL' baixa TODOS os sets do filtro (pode demorar muito!); uma prévia é mostrada antes") + "\n\n"
+	s += helpStyle.Render("enter: confirmar • esc: voltar")
+	return s
+}
+
+func (m model) renderCardInput() string {
+	s := titleStyle.Render("🃏 Download por Carta") + "\n\n"
+	s += "Digite o nome da carta:\n" + m.textInput.View() + "\n"
+	if m.ambiguousName != "" {
+		s += warningStyle.Render(fmt.Sprintf("⚠️ '%s' corresponde a várias cartas, escolha uma:", m.ambiguousName)) + "\n"
+	}
+	for i, name := range m.suggestions {
+		cursor := " "
+		if m.suggestionCursor == i {
+			cursor = selectedStyle.Render("▶")
+		}
+		s += fmt.Sprintf("%s %s\n", cursor, name)
+	}
+	s += "\n"
+	for _, log := range m.logs {
+		s += log + "\n"
+	}
+	s += infoStyle.Render("💡 Exemplo: Lightning Bolt, Black Lotus, Jace, etc.") + "\n\n"
+	if len(m.suggestions) > 0 {
+		s += helpStyle.Render("↑/↓: escolher sugestão • tab: completar • enter: confirmar • esc: voltar")
+	} else {
+		s += helpStyle.Render("enter: confirmar • esc: voltar")
+	}
+	return s
+}
+
+// clearSuggestions esconde as sugestões e a lista de desambiguação
+func (m *model) clearSuggestions() {
+	m.suggestions = nil
+	m.suggestionCursor = -1
+	m.ambiguousName = ""
+}
+
+func (m model) renderSearchInput() string {
+	s := titleStyle.Render("🔍 Download por Busca") + "\n\n"
+	s += "Digite a busca (sintaxe do Scryfall):\n" + m.textInput.View() + "\n\n"
+	s += infoStyle.Render("💡 Exemplos:") + "\n"
+	s += "  • Dragões míticos recentes: t:dragon r:mythic year>=2021\n"
+	s += "  • Por artista ou ilustração: a:\"Rebecca Guay\" / art:squirrel\n"
+	s += "  • Arte completa, todas as impressões: is:fullart unique:prints\n\n"
+
+	for _, log := range m.logs {
+		s += log + "\n"
+	}
+	if m.searchQuery != "" {
+		if m.searchHits == 0 {
+			s += warningStyle.Render("Nenhuma carta encontrada") + "\n\n"
+			s += helpStyle.Render("edite a busca • esc: voltar")
+			return s
+		}
+		s += successStyle.Render(fmt.Sprintf("🔎 %d cartas encontradas", m.searchHits)) + "\n\n"
+		s += helpStyle.Render("enter: iniciar download • edite a busca para refazer • esc: voltar")
+		return s
+	}
+	s += helpStyle.Render("enter: buscar • esc: voltar")
+	return s
+}
+
+func (m model) renderDeckInput() string {
+	s := titleStyle.Render("📜 Importar Decklist") + "\n\n"
+	s += "Digite o caminho do arquivo do deck:\n" + m.textInput.View() + "\n\n"
+	s += infoStyle.Render("💡 Formatos aceitos (detectados automaticamente):") + "\n"
+	s += "  • Texto: 4 Lightning Bolt / 1 Black Lotus (LEA) 232 / SB: 2 Duress\n"
+	s += "  • MTG Arena: 4 Opt (XLN) 65\n  • MTGO: arquivo .dek\n  • CSV do Moxfield ou Archidekt\n\n"
+	s += helpStyle.Render("enter: confirmar • esc: voltar")
+	return s
+}
+
+func (m model) renderJobs() string {
+	s := titleStyle.Render("♻️ Retomar Downloads") + "\n\n"
+	if m.jobs == nil {
+		s += m.spinner.View() + " Carregando jobs...\n\n"
+	} else if len(m.jobs) == 0 {
+		s += infoStyle.Render("Nenhum download interrompido em "+m.config.DownloadDir) + "\n\n"
+	} else {
+		for i, job := range m.jobs {
+			cursor := " "
+			if m.jobCursor == i {
+				cursor = selectedStyle.Render("▶")
+			}
+			s += fmt.Sprintf("%s %s\n", cursor, job.summary())
+		}
+		s += "\n"
+	}
+	s += helpStyle.Render("↑/↓: navegar • enter: retomar • d: descartar • esc: voltar")
+	return s
+}
+
+func (m model) renderDownload() string {
+	s := titleStyle.Render("📥 Download em Progresso") + "\n\n"
+
+	current := atomic.LoadInt64(&m.downloader.completedTasks)
+	total := atomic.LoadInt64(&m.downloader.totalTasks)
+
+	if total > 0 {
+		percent := float64(current) / float64(total)
+		s += fmt.Sprintf("Progresso: %d/%d (%.1f%%)\n", current, total, percent*100)
+		if retries := atomic.LoadInt64(&m.downloader.retryCount); retries > 0 {
+			s += warningStyle.Render(fmt.Sprintf("🔁 Retentativas: %d", retries)) + "\n"
+		}
+		if api, images := m.downloader.apiLimiter.throttled(), m.downloader.imageLimiter.throttled(); api > 0 || images > 0 {
+			s += warningStyle.Render(fmt.Sprintf("⏳ Limitando requisições (API: %d aguardando, imagens: %d aguardando)", api, images)) + "\n"
+		}
+		s += m.progress.View() + "\n\n"
+	} else {
+		s += m.spinner.View() + " Preparando download...\n\n"
+	}
+	if m.cancel != nil && m.downloader.pause.isPaused() {
+		s += warningStyle.Render("⏸️ Pausado: nenhuma requisição nova até continuar") + "\n\n"
+	}
+
+	if len(m.logs) > 0 {
+		s += infoStyle.Render("📋 Log de Atividades:") + "\n"
+		startIndex := 0
+		if len(m.logs) > 12 {
+			startIndex = len(m.logs) - 12
+		}
+
+		for i := startIndex; i < len(m.logs); i++ {
+			log := m.logs[i]
+			if strings.HasPrefix(log, "✅") || strings.HasPrefix(log, "✓") {
+				s += successStyle.Render(log) + "\n"
+			} else if strings.HasPrefix(log, "❌") || strings.HasPrefix(log, "✗") {
+				s += errorStyle.Render(log) + "\n"
+			} else if strings.HasPrefix(log, "🚀") || strings.HasPrefix(log, "🎉") {
+				s += warningStyle.Render(log) + "\n"
+			} else {
+				s += log + "\n"
+			}
+		}
+	}
+
+	switch {
+	case m.confirmCancel:
+		s += "\n" + warningStyle.Render("Cancelar o download? O que faltou fica salvo para retomar depois (s/n)")
+	case m.cancel != nil:
+		s += "\n" + helpStyle.Render("p: pausar/continuar • esc: cancelar download")
+	default:
+		s += "\n" + helpStyle.Render("esc: voltar ao menu")
+	}
+	return s
+}
+
+func (m model) renderConfig() string {
+	s := titleStyle.Render("⚙️ Configurações") + "\n\n"
+
+	options := []string{
+		fmt.Sprintf("📁 Pasta de Download: %s", m.config.DownloadDir),
+		fmt.Sprintf("🎨 Qualidade: %s", m.config.Quality),
+		fmt.Sprintf("📝 Nome dos arquivos: %s", m.config.NameTemplate),
+		fmt.Sprintf("📦 Preset: %s", presetLabel(m.config.Preset)),
+		fmt.Sprintf("🖼️ Variantes: %s", variantsLabel(m.config.Variants)),
+		fmt.Sprintf("🔄 Faces: %s", faceNamesLabel(m.config.FaceNames)),
+		fmt.Sprintf("🂠 Verso padrão: %s", yesNo(m.config.CardBack)),
+		fmt.Sprintf("🪙 Tokens e art series: %s", relatedLabel(m.config)),
+		fmt.Sprintf("🗣️ Idioma: %s", m.config.Lang),
+		fmt.Sprintf("🔤 Sem tradução: %s", langFallbackLabel(m.config.LangFallback)),
+		fmt.Sprintf("📚 Catálogo offline: %s", yesNo(m.config.UseCatalog)),
+		fmt.Sprintf("⚡ Workers: %d", m.config.MaxWorkers),
+		fmt.Sprintf("🌐 API: %s", m.config.APIBase),
+		fmt.Sprintf("🔁 Retentativas: %d", m.config.MaxRetries),
+		fmt.Sprintf("⏱️ Espera inicial: %dms", m.config.RetryDelayMs),
+		fmt.Sprintf("🚦 Limite da API: %s req/s", formatRate(m.config.APIRate)),
+		fmt.Sprintf("🖼️ Limite de imagens: %s req/s", formatRate(m.config.ImageRate)),
+		"🔙 Voltar",
+	}
+
+	for i, option := range options {
+		cursor := " "
+		if m.currentMenu == i {
+			cursor = selectedStyle.Render("▶")
+		}
+		s += fmt.Sprintf("%s %s\n", cursor, option)
+	}
+
+	if m.textInput.Focused() {
+		s += "\n" + infoStyle.Render("Digite o novo valor:") + "\n" + m.textInput.View()
+	}
+
+	s += "\n\n" + infoStyle.Render("💡 Dicas:") + "\n"
+	s += "  • Pasta: Use caminho completo (ex: C:\\MinhasCartas)\n"
+	s += "  • Qualidade: small, normal, large, png, art_crop, border_crop; vários separados por vírgula\n"
+	s += "  • Nome: {set} {collector_number} {name} {face} {lang} {quality} {rarity} {artist}; / cria pastas\n"
+	s += "  • Preset: Layout de um cliente (Forge, Cockatrice, XMage); substitui o template\n"
+	s += "  • Variantes: Todas as artes de uma carta no set (terrenos básicos etc.) ou só a primeira\n"
+	s += "  • Faces: Cartas de dupla face com o nome de cada face ou <carta>_front e <carta>_back\n"
+	s += "  • Verso padrão: Baixa o verso das cartas de Magic uma vez em cada pasta\n"
+	s += "  • Tokens: Inclui tokens, emblemas e art series ligados a cada set baixado\n"
+	s += "  • Workers: Número de downloads simultâneos (1-50)\n"
+	s += "  • API: Servidor compatível com o Scryfall (espelho interno ou local)\n"
+	s += "  • Retentativas: Repetições após erro de rede, 429 ou 5xx (espera dobra a cada vez)\n"
+	s += "  • Limites: Requisições por segundo; o Scryfall pede no máximo 10 na API (0 = sem limite)\n\n"
+
+	if len(m.logs) > 0 && m.currentMenu < configBack {
+		s += infoStyle.Render("📋 Últimas alterações:") + "\n"
+		startIndex := len(m.logs) - 3
+		if startIndex < 0 {
+			startIndex = 0
+		}
+		for i := startIndex; i < len(m.logs); i++ {
+			s += m.logs[i] + "\n"
+		}
+		s += "\n"
+	}
+
+	s += helpStyle.Render("↑/↓: navegar • enter: editar • esc: voltar")
+	return s
+}
+
+func (m model) fetchSetsCmd() tea.Cmd {
+	return func() tea.Msg {
+		sets, err := m.downloader.fetchSets(context.Background())
+		if err != nil {
+			return errorMsg{err}
+		}
+		return setListMsg(sets)
+	}
+}
+
+func (m model) downloadMultipleSetsCmd(ctx context.Context, setCodes []string) tea.Cmd {
+	return func() tea.Msg {
+		return m.downloader.downloadSets(ctx, m.sets, setCodes)
+	}
+}
+
+func (m model) downloadCardCmd(ctx context.Context, cardName string, exact bool) tea.Cmd {
+	return func() tea.Msg {
+		result, err := m.downloader.downloadCard(ctx, cardName, exact)
+		if errors.Is(err, errAmbiguousCard) {
+			if names, _ := m.downloader.autocomplete(context.Background(), cardName); len(names) > 0 {
+				return ambiguousCardMsg{name: cardName, names: names}
+			}
+		}
+		if err != nil {
+			return errorMsg{err}
+		}
+		return result
+	}
+}
+
+// autocompleteTick agenda a consulta de sugestões para daqui a pouco, se o texto não mudar
+func autocompleteTick(query string) tea.Cmd {
+	return tea.Tick(250*time.Millisecond, func(time.Time) tea.Msg { return autocompleteTickMsg{query} })
+}
+
+func (m model) autocompleteCmd(query string) tea.Cmd {
+	return func() tea.Msg {
+		// Sugestões são opcionais: numa falha o campo continua funcionando sem elas
+		names, _ := m.downloader.autocomplete(context.Background(), query)
+		return suggestionsMsg{query: query, names: names}
+	}
+}
+
+func (m model) downloadDeckCmd(ctx context.Context, path string) tea.Cmd {
+	return func() tea.Msg {
+		deck, err := readDecklist(path, "")
+		if err != nil {
+			return errorMsg{err}
+		}
+		return m.downloader.downloadDeck(ctx, deck)
+	}
+}
+
+func (m model) countSearchCmd(query string) tea.Cmd {
+	return func() tea.Msg {
+		total, err := m.downloader.countSearch(context.Background(), query)
+		if err != nil {
+			return errorMsg{err}
+		}
+		return searchCountMsg{query: query, total: total}
+	}
+}
+
+func (m model) downloadSearchCmd(ctx context.Context, query string) tea.Cmd {
+	return func() tea.Msg {
+		return m.downloader.downloadSearch(ctx, query)
+	}
+}
+
+func (m model) loadJobsCmd() tea.Cmd {
+	return func() tea.Msg {
+		jobs, err := listJobs(m.config.DownloadDir)
+		if err != nil {
+			return errorMsg{err}
+		}
+		if jobs == nil {
+			jobs = []*jobJournal{}
+		}
+		return jobListMsg(jobs)
+	}
+}
+
+func (m model) resumeJobCmd(ctx context.Context, job *jobJournal) tea.Cmd {
+	return func() tea.Msg {
+		return m.downloader.resumeJob(ctx, job, m.sets)
+	}
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func runTUI(cfg Config, configPath string) int {
+	p := tea.NewProgram(initialModel(cfg, configPath), tea.WithAltScreen())
+	if _, err := p.Run(); err != nil {
+		fmt.Printf("Erro: %v", err)
+		return exitFailure
+	}
+	return exitOK
+}
+
+func main() {
+	os.Exit(run(os.Args[1:]))
+}
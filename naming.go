@@ -0,0 +1,286 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// O nome de cada imagem vem de um template com placeholders, relativo à pasta de download.
+// "/" no template cria subpastas e a extensão é acrescentada conforme o tipo de imagem.
+// O padrão reproduz o layout original: <SET>/<nome>.full.jpg.
+const defaultNameTemplate = "{set}/{name}.full"
+
+var namePlaceholders = []string{"set", "collector_number", "name", "face", "lang", "quality", "rarity", "artist"}
+
+var placeholderPattern = regexp.MustCompile(`\{([a-z_]+)\}`)
+
+// imageNameFields são os valores disponíveis para o template de uma imagem
+type imageNameFields struct {
+	Set             string // código em maiúsculas, como nas pastas
+	CollectorNumber string
+	Name            string // nome da face quando a carta tem uma imagem por face
+	Face            string // front/back em cartas de dupla face, vazio nas demais
+	Lang            string
+	Quality         string // tipo de imagem efetivamente baixado
+	Rarity          string
+	Artist          string
+}
+
+func (f imageNameFields) value(placeholder string) string {
+	switch placeholder {
+	case "set":
+		return f.Set
+	case "collector_number":
+		return f.CollectorNumber
+	case "name":
+		return f.Name
+	case "face":
+		return f.Face
+	case "lang":
+		return f.Lang
+	case "quality":
+		return f.Quality
+	case "rarity":
+		return f.Rarity
+	case "artist":
+		return f.Artist
+	}
+	return ""
+}
+
+// faceLabel nomeia a posição de uma face no placeholder {face}
+func faceLabel(index int) string {
+	switch index {
+	case 0:
+		return "front"
+	case 1:
+		return "back"
+	}
+	return fmt.Sprintf("face%d", index+1)
+}
+
+// validateNameTemplate rejeita placeholders desconhecidos e caminhos que sairiam da pasta de download
+func validateNameTemplate(template string) error {
+	if strings.TrimSpace(template) == "" {
+		return fmt.Errorf("template de nome não pode ser vazio")
+	}
+	for _, match := range placeholderPattern.FindAllStringSubmatch(template, -1) {
+		if !containsString(namePlaceholders, match[1]) {
+			return fmt.Errorf("placeholder desconhecido {%s} (use %s)", match[1], "{"+strings.Join(namePlaceholders, "}, {")+"}")
+		}
+	}
+	normalized := strings.ReplaceAll(template, "\\", "/")
+	if strings.HasPrefix(normalized, "/") || strings.Contains(normalized, ":") {
+		return fmt.Errorf("template de nome deve ser relativo à pasta de download")
+	}
+	for _, segment := range strings.Split(normalized, "/") {
+		if strings.TrimSpace(segment) == ".." {
+			return fmt.Errorf("template de nome não pode conter \"..\"")
+		}
+	}
+	return nil
+}
+
+// expandNameTemplate monta o caminho relativo (separado por "/") de uma imagem, sem extensão.
+// Os valores são sanitizados, então só as barras do próprio template criam pastas.
+func expandNameTemplate(template string, fields imageNameFields) string {
+	expanded := placeholderPattern.ReplaceAllStringFunc(template, func(match string) string {
+		return sanitizeFileName(fields.value(match[1 : len(match)-1]))
+	})
+
+	// Placeholders vazios (ex: {face} numa carta comum) não devem deixar separadores soltos
+	var segments []string
+	for _, segment := range strings.Split(strings.ReplaceAll(expanded, "\\", "/"), "/") {
+		if segment = strings.Trim(segment, " _-"); segment != "" && segment != "." && segment != ".." {
+			segments = append(segments, segment)
+		}
+	}
+	if len(segments) == 0 {
+		return sanitizeFileName(fields.Name)
+	}
+	return strings.Join(segments, "/")
+}
+
+// imageExt é a extensão do arquivo para um tipo de imagem do Scryfall
+func imageExt(imageType string) string {
+	if imageType == "png" {
+		return ".png"
+	}
+	return ".jpg"
+}
+
+// isCropType indica os recortes, que mostram só parte da carta e não podem ser trocados por outro tipo
+func isCropType(imageType string) bool {
+	return imageType == "art_crop" || imageType == "border_crop"
+}
+
+// imageTypeFolder coloca a imagem numa subpasta com o nome do tipo, ao lado da pasta original
+func imageTypeFolder(p, imageType string) string {
+	dir, file := path.Split(p)
+	return dir + imageType + "/" + file
+}
+
+// splitImageExt separa a extensão de um caminho, tratando o ".full" do Forge como parte dela
+func splitImageExt(p string) (base, ext string) {
+	ext = path.Ext(p)
+	base = strings.TrimSuffix(p, ext)
+	if strings.HasSuffix(base, ".full") {
+		return strings.TrimSuffix(base, ".full"), ".full" + ext
+	}
+	return base, ext
+}
+
+// exportPreset é o layout de pastas e nomes esperado por um cliente de jogo. Com um preset
+// ativo, a pasta de download deve ser a pasta de dados do cliente.
+type exportPreset struct {
+	name     string
+	label    string
+	template string
+	numbered bool // artes alternativas como Nome2, Nome3 em vez de "Nome (número)"
+}
+
+var exportPresets = []exportPreset{
+	{name: "forge", label: "Forge", template: "pics/cards/{set}/{name}.full", numbered: true},
+	{name: "cockatrice", label: "Cockatrice", template: "pics/CUSTOM/{set}/{name}"},
+	{name: "xmage", label: "XMage", template: "plugins/images/{set}/{name}.{collector_number}.full"},
+}
+
+func findExportPreset(name string) *exportPreset {
+	for i := range exportPresets {
+		if exportPresets[i].name == name {
+			return &exportPresets[i]
+		}
+	}
+	return nil
+}
+
+func exportPresetNames() []string {
+	var names []string
+	for _, preset := range exportPresets {
+		names = append(names, preset.name)
+	}
+	return names
+}
+
+// Opções de variants: manter todas as artes de uma carta no set ou só a primeira (o padrão)
+var variantOptions = []string{"all", "first"}
+
+// Verso padrão das cartas de Magic no Scryfall
+const defaultCardBackURL = "https://backs.scryfall.io/large/0/a/0aeebaf5-8c7d-4636-9e82-8c27447861f7.jpg"
+
+// Opções de face_names: cada face com o próprio nome ou o nome da carta com _front/_back
+var faceNameOptions = []string{"face", "paired"}
+
+// pathPlanner garante que imagens diferentes de um mesmo job não recebam o mesmo caminho
+type pathPlanner struct {
+	used     map[string]string // caminho em minúsculas (Windows não diferencia) → URL
+	numbered bool
+	keepAll  bool
+
+	cardBackURL string          // vazio quando o verso não é baixado
+	backDirs    map[string]bool // pastas que já receberam o verso
+
+	kept, collapsed int // variantes renomeadas e descartadas
+}
+
+func (d *Downloader) newPathPlanner() *pathPlanner {
+	planner := &pathPlanner{used: make(map[string]string), numbered: d.numberedVariants, keepAll: d.variants != "first"}
+	if d.cardBack {
+		planner.cardBackURL = d.cardBackURL
+		planner.backDirs = make(map[string]bool)
+	}
+	return planner
+}
+
+// assign reserva o caminho de cada task. Uma imagem repetida é descartada; uma imagem diferente
+// que cairia num caminho ocupado (terrenos básicos, artes alternativas) é uma variante: com keepAll
+// recebe o número de colecionador e, se ainda preciso, um contador, senão é descartada. No modo
+// numerado (Forge) recebe só o contador, colado ao nome: Forest.full.jpg, Forest2.full.jpg.
+func (p *pathPlanner) assign(tasks []imageTask, collectorNumber string) []imageTask {
+	var assigned []imageTask
+	for _, task := range tasks {
+		if url, ok := p.used[strings.ToLower(task.Path)]; ok {
+			if url == task.URL {
+				continue
+			}
+			if !p.keepAll {
+				p.collapsed++
+				continue
+			}
+			p.kept++
+			task.Path = p.disambiguate(task.Path, collectorNumber)
+		}
+		p.used[strings.ToLower(task.Path)] = task.URL
+		assigned = append(assigned, task)
+
+		if dir := path.Dir(task.Path); p.cardBackURL != "" && !p.backDirs[dir] {
+			p.backDirs[dir] = true
+			assigned = append(assigned, p.cardBackTask(dir, task.SetCode))
+		}
+	}
+	return assigned
+}
+
+// reserve marca como ocupados os caminhos de tasks planejadas antes (ao retomar um job), para que
+// as cartas planejadas agora não recebam o mesmo caminho nem repitam o verso padrão numa pasta
+func (p *pathPlanner) reserve(tasks []imageTask) {
+	for _, task := range tasks {
+		taskPath := task.Path
+		if taskPath == "" {
+			// Journals antigos: layout original <SET>/<nome>.full.jpg, como em imagePath
+			taskPath = strings.ToUpper(task.SetCode) + "/" + sanitizeFileName(task.FileName) + ".full.jpg"
+		}
+		p.used[strings.ToLower(taskPath)] = task.URL
+		if p.cardBackURL != "" && task.FileName == "cardback" {
+			p.backDirs[path.Dir(taskPath)] = true
+		}
+	}
+}
+
+// cardBackTask baixa o verso padrão para uma pasta, uma única vez por job (e por pasta, já que
+// downloadImage pula arquivos existentes)
+func (p *pathPlanner) cardBackTask(dir, setCode string) imageTask {
+	ext := path.Ext(strings.SplitN(p.cardBackURL, "?", 2)[0])
+	if ext != ".png" {
+		ext = ".jpg"
+	}
+	backPath := path.Join(dir, "cardback"+ext)
+	p.used[strings.ToLower(backPath)] = p.cardBackURL
+	return imageTask{URL: p.cardBackURL, FileName: "cardback", SetCode: setCode, Path: backPath}
+}
+
+func (p *pathPlanner) disambiguate(taken, collectorNumber string) string {
+	base, ext := splitImageExt(taken)
+	if p.numbered {
+		for i := 2; ; i++ {
+			candidate := base + strconv.Itoa(i) + ext
+			if _, ok := p.used[strings.ToLower(candidate)]; !ok {
+				return candidate
+			}
+		}
+	}
+	if collectorNumber = sanitizeFileName(collectorNumber); collectorNumber != "" {
+		candidate := fmt.Sprintf("%s (%s)%s", base, collectorNumber, ext)
+		if _, ok := p.used[strings.ToLower(candidate)]; !ok {
+			return candidate
+		}
+	}
+	for i := 2; ; i++ {
+		candidate := fmt.Sprintf("%s (%d)%s", base, i, ext)
+		if _, ok := p.used[strings.ToLower(candidate)]; !ok {
+			return candidate
+		}
+	}
+}
+
+// planCards gera as tasks das cartas com caminhos únicos dentro do planner
+func (d *Downloader) planCards(planner *pathPlanner, cards []Card) []imageTask {
+	var tasks []imageTask
+	for _, card := range cards {
+		tasks = append(tasks, planner.assign(d.processCard(card), card.CollectorNumber)...)
+	}
+	return tasks
+}
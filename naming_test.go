@@ -0,0 +1,212 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExpandNameTemplate(t *testing.T) {
+	bolt := imageNameFields{Set: "M21", CollectorNumber: "199", Name: "Lightning Bolt", Lang: "en", Quality: "large", Rarity: "common", Artist: "Christopher Moeller"}
+	delver := imageNameFields{Set: "ISD", CollectorNumber: "51", Name: "Delver of Secrets", Face: "front", Lang: "en"}
+
+	tests := []struct {
+		template string
+		fields   imageNameFields
+		want     string
+	}{
+		{defaultNameTemplate, bolt, "M21/Lightning Bolt.full"},
+		{"{set}/{collector_number} - {name}", bolt, "M21/199 - Lightning Bolt"},
+		{"{lang}/{quality}/{rarity}/{artist}/{name}", bolt, "en/large/common/Christopher Moeller/Lightning Bolt"},
+		// {face} vazio não deixa separadores soltos
+		{"{set}/{name}_{face}", bolt, "M21/Lightning Bolt"},
+		{"{set}/{name}_{face}", delver, "ISD/Delver of Secrets_front"},
+		{"{face}/{name}", bolt, "Lightning Bolt"},
+		// Só com placeholders vazios, cai no nome da carta
+		{"{face}", bolt, "Lightning Bolt"},
+		// Barras nos valores não criam pastas
+		{"{set}/{name}", imageNameFields{Set: "MH2", Name: "Fire // Ice"}, "MH2/" + sanitizeFileName("Fire // Ice")},
+		{"{set}/../{name}", bolt, "M21/Lightning Bolt"},
+	}
+	for _, tt := range tests {
+		if got := expandNameTemplate(tt.template, tt.fields); got != tt.want {
+			t.Errorf("expandNameTemplate(%q) = %q, esperado %q", tt.template, got, tt.want)
+		}
+	}
+}
+
+func namingCard(set, number, name, url string) Card {
+	return Card{Set: set, CollectorNumber: number, Name: name, Layout: "normal", Lang: "en", ImageURIs: map[string]string{"large": url}}
+}
+
+func taskPaths(tasks []imageTask) []string {
+	var paths []string
+	for _, task := range tasks {
+		paths = append(paths, task.Path)
+	}
+	return paths
+}
+
+// Terrenos básicos: várias artes com o mesmo nome no mesmo set, uma impressão repetida e uma
+// variante cujo número de colecionador já está ocupado
+var basicLands = []Card{
+	namingCard("tst", "2", "Forest", "http://img/tst/2.jpg"),
+	namingCard("tst", "3", "Forest", "http://img/tst/3.jpg"),
+	namingCard("tst", "2", "Forest", "http://img/tst/2.jpg"),
+	namingCard("tst", "3", "Forest", "http://img/tst/3b.jpg"),
+	namingCard("tst", "1", "Lightning Bolt", "http://img/tst/1.jpg"),
+}
+
+func TestPlanCardsPresets(t *testing.T) {
+	tests := []struct {
+		name          string
+		preset        string
+		variants      string
+		want          []string
+		kept, dropped int
+	}{
+		{
+			name:     "template padrão",
+			variants: "all",
+			want:     []string{"TST/Forest.full.jpg", "TST/Forest (3).full.jpg", "TST/Forest (2).full.jpg", "TST/Lightning Bolt.full.jpg"},
+			kept:     2,
+		},
+		{
+			name:     "forge numera colado ao nome",
+			preset:   "forge",
+			variants: "all",
+			want:     []string{"pics/cards/TST/Forest.full.jpg", "pics/cards/TST/Forest2.full.jpg", "pics/cards/TST/Forest3.full.jpg", "pics/cards/TST/Lightning Bolt.full.jpg"},
+			kept:     2,
+		},
+		{
+			name:     "cockatrice",
+			preset:   "cockatrice",
+			variants: "all",
+			want:     []string{"pics/CUSTOM/TST/Forest.jpg", "pics/CUSTOM/TST/Forest (3).jpg", "pics/CUSTOM/TST/Forest (2).jpg", "pics/CUSTOM/TST/Lightning Bolt.jpg"},
+			kept:     2,
+		},
+		{
+			name:     "xmage já separa pelo número",
+			preset:   "xmage",
+			variants: "all",
+			want:     []string{"plugins/images/TST/Forest.2.full.jpg", "plugins/images/TST/Forest.3.full.jpg", "plugins/images/TST/Forest.3 (3).full.jpg", "plugins/images/TST/Lightning Bolt.1.full.jpg"},
+			kept:     1,
+		},
+		{
+			name:     "variants first descarta as outras artes",
+			variants: "first",
+			want:     []string{"TST/Forest.full.jpg", "TST/Lightning Bolt.full.jpg"},
+			dropped:  2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := defaultConfig()
+			cfg.Preset = tt.preset
+			cfg.Variants = tt.variants
+			d := NewDownloader(cfg)
+
+			planner := d.newPathPlanner()
+			got := taskPaths(d.planCards(planner, basicLands))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("caminhos:\n got %q\nwant %q", got, tt.want)
+			}
+			if planner.kept != tt.kept || planner.collapsed != tt.dropped {
+				t.Errorf("variantes = %d mantidas, %d descartadas; esperado %d, %d", planner.kept, planner.collapsed, tt.kept, tt.dropped)
+			}
+		})
+	}
+}
+
+func TestPlanCardsCardBack(t *testing.T) {
+	cfg := defaultConfig()
+	cfg.CardBack = true
+	cfg.CardBackURL = "http://img/back.jpg"
+	cards := []Card{
+		namingCard("tst", "1", "Lightning Bolt", "http://img/tst/1.jpg"),
+		namingCard("tst", "2", "Forest", "http://img/tst/2.jpg"),
+		namingCard("two", "1", "Opt", "http://img/two/1.jpg"),
+	}
+
+	tests := []struct {
+		template string
+		want     []string
+	}{
+		{
+			template: defaultNameTemplate,
+			want:     []string{"TST/Lightning Bolt.full.jpg", "TST/cardback.jpg", "TST/Forest.full.jpg", "TWO/Opt.full.jpg", "TWO/cardback.jpg"},
+		},
+		{
+			// Sem {set} todas as imagens caem na mesma pasta: um verso só
+			template: "{name}",
+			want:     []string{"Lightning Bolt.jpg", "cardback.jpg", "Forest.jpg", "Opt.jpg"},
+		},
+	}
+	for _, tt := range tests {
+		cfg.NameTemplate = tt.template
+		d := NewDownloader(cfg)
+		got := taskPaths(d.planCards(d.newPathPlanner(), cards))
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("template %q:\n got %q\nwant %q", tt.template, got, tt.want)
+		}
+	}
+}
+
+// Ao retomar um job, as tasks do journal ocupam seus caminhos antes das cartas planejadas agora
+func TestPathPlannerReserve(t *testing.T) {
+	cfg := defaultConfig()
+	cfg.Variants = "all"
+	cfg.CardBack = true
+	cfg.CardBackURL = "http://img/back.jpg"
+
+	tests := []struct {
+		name     string
+		template string
+		preset   string
+		journal  []imageTask
+		cards    []Card
+		want     []string
+	}{
+		{
+			name:     "template sem {set}",
+			template: "{name}",
+			journal: []imageTask{
+				{ID: 1, URL: "http://img/tst/1.jpg", FileName: "Lightning Bolt", SetCode: "tst", Path: "Lightning Bolt.jpg"},
+				{ID: 2, URL: "http://img/old-back.jpg", FileName: "cardback", SetCode: "tst", Path: "cardback.jpg"},
+			},
+			cards: []Card{namingCard("two", "2", "Lightning Bolt", "http://img/two/2.jpg"), namingCard("two", "1", "Opt", "http://img/two/1.jpg")},
+			want:  []string{"Lightning Bolt (2).jpg", "Opt.jpg"},
+		},
+		{
+			name:   "forge continua a numeração",
+			preset: "forge",
+			journal: []imageTask{
+				{ID: 1, URL: "http://img/tst/2.jpg", FileName: "Forest", SetCode: "tst", Path: "pics/cards/TST/Forest.full.jpg"},
+				{ID: 2, URL: "http://img/back.jpg", FileName: "cardback", SetCode: "tst", Path: "pics/cards/TST/cardback.jpg"},
+				{ID: 3, URL: "http://img/tst/3.jpg", FileName: "Forest", SetCode: "tst", Path: "pics/cards/TST/Forest2.full.jpg"},
+			},
+			cards: []Card{namingCard("tst", "4", "Forest", "http://img/tst/4.jpg")},
+			want:  []string{"pics/cards/TST/Forest3.full.jpg"},
+		},
+		{
+			name:     "journal antigo sem Path",
+			template: defaultNameTemplate,
+			journal:  []imageTask{{ID: 1, URL: "http://img/tst/2.jpg", FileName: "Forest", SetCode: "tst"}},
+			cards:    []Card{namingCard("tst", "3", "Forest", "http://img/tst/3.jpg")},
+			want:     []string{"TST/Forest (3).full.jpg", "TST/cardback.jpg"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg.NameTemplate = tt.template
+			cfg.Preset = tt.preset
+			d := NewDownloader(cfg)
+			planner := d.newPathPlanner()
+			planner.reserve(tt.journal)
+			got := taskPaths(d.planCards(planner, tt.cards))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("caminhos:\n got %q\nwant %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"sync"
+)
+
+// pauseGate segura novas requisições enquanto o download está pausado. O valor zero está liberado.
+type pauseGate struct {
+	mu     sync.Mutex
+	paused bool
+	resume chan struct{}
+}
+
+func (g *pauseGate) set(paused bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if paused == g.paused {
+		return
+	}
+	g.paused = paused
+	if paused {
+		g.resume = make(chan struct{})
+	} else {
+		close(g.resume)
+	}
+}
+
+func (g *pauseGate) isPaused() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.paused
+}
+
+// wait bloqueia enquanto estiver pausado; retorna erro se o contexto for cancelado durante a pausa
+func (g *pauseGate) wait(ctx context.Context) error {
+	g.mu.Lock()
+	if !g.paused {
+		g.mu.Unlock()
+		return ctx.Err()
+	}
+	resume := g.resume
+	g.mu.Unlock()
+
+	select {
+	case <-resume:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
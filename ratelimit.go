@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// rateLimiter é um token bucket: acumula até burst requisições e libera rate por segundo.
+// Com rate <= 0 não há limite.
+type rateLimiter struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+
+	waiting int64 // requisições aguardando liberação, lido pela TUI
+}
+
+func newRateLimiter(rate float64, burst int) *rateLimiter {
+	l := &rateLimiter{last: time.Now()}
+	l.setRate(rate, burst)
+	l.tokens = l.burst
+	return l
+}
+
+func (l *rateLimiter) setRate(rate float64, burst int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.rate = rate
+	l.burst = math.Max(1, float64(burst))
+	l.tokens = math.Min(l.tokens, l.burst)
+}
+
+// wait bloqueia até a próxima requisição ser permitida ou o contexto ser cancelado. A vaga é
+// reservada antes de dormir, então chamadas concorrentes formam fila em vez de disputar o mesmo token.
+func (l *rateLimiter) wait(ctx context.Context) error {
+	l.mu.Lock()
+	if l.rate <= 0 {
+		l.mu.Unlock()
+		return nil
+	}
+	now := time.Now()
+	l.tokens = math.Min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	l.last = now
+	l.tokens--
+
+	var delay time.Duration
+	if l.tokens < 0 {
+		delay = time.Duration(-l.tokens / l.rate * float64(time.Second))
+	}
+	l.mu.Unlock()
+
+	if delay <= 0 {
+		return nil
+	}
+	atomic.AddInt64(&l.waiting, 1)
+	defer atomic.AddInt64(&l.waiting, -1)
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// throttled retorna quantas requisições estão esperando no momento
+func (l *rateLimiter) throttled() int64 {
+	return atomic.LoadInt64(&l.waiting)
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"strings"
+)
+
+// Cartas ligadas a um set que não vêm na busca do próprio set: os sets filhos (parent_set_code)
+// de tokens e art series e os tokens e emblemas citados em all_parts de cada carta.
+
+// Tipos de set filho incluídos com a opção related (art series são "memorabilia")
+var relatedSetTypes = map[string]bool{"token": true, "memorabilia": true}
+
+// RelatedCard é uma entrada de all_parts
+type RelatedCard struct {
+	ID        string `json:"id"`
+	Component string `json:"component"`
+	Name      string `json:"name"`
+	TypeLine  string `json:"type_line"`
+	URI       string `json:"uri"`
+}
+
+// isTokenPart indica tokens e emblemas; as demais partes (combo_piece, meld) são cartas comuns
+func isTokenPart(part RelatedCard) bool {
+	return part.Component == "token" || strings.HasPrefix(part.TypeLine, "Emblem")
+}
+
+// relatedCards busca as cartas ligadas ao set parent. Falhas são registradas no log e não
+// impedem o download do set.
+func (d *Downloader) relatedCards(ctx context.Context, sets []Set, parent *Set, cards []Card) []Card {
+	var related []Card
+	seen := make(map[string]bool)
+	for _, card := range cards {
+		seen[card.ID] = true
+	}
+
+	for _, child := range sets {
+		if !strings.EqualFold(child.ParentSetCode, parent.Code) || !relatedSetTypes[child.SetType] {
+			continue
+		}
+		childCards, err := d.fetchLocalizedCards(ctx, child.SearchURI)
+		if err != nil {
+			d.logf("Falha ao buscar cartas de %s: %v", strings.ToUpper(child.Code), err)
+			continue
+		}
+		d.logf("Set %s (%s): %d cartas", strings.ToUpper(child.Code), child.SetType, len(childCards))
+		for _, card := range childCards {
+			if !seen[card.ID] {
+				seen[card.ID] = true
+				related = append(related, card)
+			}
+		}
+	}
+
+	for _, card := range cards {
+		for _, part := range card.AllParts {
+			if seen[part.ID] || !isTokenPart(part) {
+				continue
+			}
+			seen[part.ID] = true
+			token, err := d.fetchCardURL(ctx, d.resolveAPIURL(part.URI))
+			if err != nil {
+				d.logf("Falha ao buscar %s de %s: %v", part.Name, card.Name, err)
+				continue
+			}
+			related = append(related, *token)
+		}
+	}
+	return related
+}
+
+// planRelated gera as tasks das cartas ligadas, na pasta do set pai (ou numa subpasta dela)
+func (d *Downloader) planRelated(planner *pathPlanner, parent *Set, cards []Card) []imageTask {
+	var tasks []imageTask
+	for _, card := range cards {
+		card.Set = parent.Code
+		cardTasks := d.processCard(card)
+		if d.relatedFolder != "" {
+			for i := range cardTasks {
+				cardTasks[i].Path = imageTypeFolder(cardTasks[i].Path, d.relatedFolder)
+			}
+		}
+		tasks = append(tasks, planner.assign(cardTasks, card.CollectorNumber)...)
+	}
+	return tasks
+}
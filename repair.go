@@ -0,0 +1,188 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	_ "image/jpeg" // Registra os decodificadores usados por validateImageFile
+	_ "image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Sufixo dos arquivos em download; só são renomeados para o nome final depois de validados
+const partialSuffix = ".part"
+
+// Marcadores do fim de cada formato, ausentes em arquivos truncados
+var (
+	jpegTrailer = []byte{0xFF, 0xD9}
+	pngTrailer  = []byte{'I', 'E', 'N', 'D', 0xAE, 0x42, 0x60, 0x82}
+)
+
+// validateImageFile confere se o arquivo é um JPEG ou PNG com cabeçalho legível e que chega até o marcador final
+func validateImageFile(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, format, err := image.DecodeConfig(file)
+	if err != nil {
+		return fmt.Errorf("imagem inválida %s: %w", filepath.Base(path), err)
+	}
+
+	trailer := jpegTrailer
+	if format == "png" {
+		trailer = pngTrailer
+	}
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	if info.Size() < int64(len(trailer)) {
+		return fmt.Errorf("imagem truncada %s", filepath.Base(path))
+	}
+	end := make([]byte, len(trailer))
+	if _, err := file.ReadAt(end, info.Size()-int64(len(trailer))); err != nil && err != io.EOF {
+		return err
+	}
+	if !bytes.Equal(end, trailer) {
+		return fmt.Errorf("imagem truncada %s", filepath.Base(path))
+	}
+	return nil
+}
+
+// findBrokenImages percorre a pasta de download atrás de arquivos temporários esquecidos e imagens inválidas
+func (d *Downloader) findBrokenImages() (partials, broken []string, err error) {
+	err = filepath.Walk(d.downloadDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if path != d.downloadDir && strings.HasPrefix(info.Name(), ".") {
+				return filepath.SkipDir // .jobs e outras pastas internas
+			}
+			return nil
+		}
+
+		switch strings.ToLower(filepath.Ext(path)) {
+		case partialSuffix:
+			partials = append(partials, path)
+		case ".jpg", ".jpeg", ".png":
+			if validateImageFile(path) != nil {
+				broken = append(broken, path)
+			}
+		}
+		return nil
+	})
+	return partials, broken, err
+}
+
+// setFromPath procura, da pasta mais interna para a mais externa, uma pasta com o código de um set
+func (d *Downloader) setFromPath(sets []Set, path string) string {
+	rel, err := filepath.Rel(d.downloadDir, path)
+	if err != nil {
+		return ""
+	}
+	dirs := strings.Split(filepath.ToSlash(filepath.Dir(rel)), "/")
+	for i := len(dirs) - 1; i >= 0; i-- {
+		if set := findSet(sets, dirs[i]); set != nil {
+			return set.Code
+		}
+	}
+	return ""
+}
+
+// repairImages remove downloads incompletos e baixa novamente as imagens corrompidas. O set de cada
+// arquivo vem da pasta com o código do set no caminho (que pode estar abaixo de pastas do preset);
+// arquivos que não correspondem a nenhuma carta do set são mantidos.
+func (d *Downloader) repairImages(ctx context.Context, sets []Set, dryRun bool) downloadCompleteMsg {
+	partials, broken, err := d.findBrokenImages()
+	if err != nil {
+		return downloadCompleteMsg{success: false, message: fmt.Sprintf("Erro ao verificar %s: %v", d.downloadDir, err)}
+	}
+	d.logf("%d arquivos temporários e %d imagens corrompidas encontrados", len(partials), len(broken))
+
+	for _, path := range partials {
+		d.logf("Temporário: %s", path)
+		if !dryRun {
+			os.Remove(path)
+		}
+	}
+
+	for _, path := range broken {
+		d.logf("Corrompida: %s", path)
+	}
+
+	if dryRun || len(broken) == 0 {
+		return downloadCompleteMsg{
+			success: true,
+			message: fmt.Sprintf("Verificação concluída: %d temporários, %d imagens corrompidas", len(partials), len(broken)),
+		}
+	}
+
+	var tasks []imageTask
+	var completed, failed, skipped []string
+	brokenBySet := make(map[string]map[string]bool)
+	for _, path := range broken {
+		setCode := d.setFromPath(sets, path)
+		if setCode == "" {
+			skipped = append(skipped, path)
+			continue
+		}
+		if brokenBySet[setCode] == nil {
+			brokenBySet[setCode] = make(map[string]bool)
+		}
+		brokenBySet[setCode][path] = true
+	}
+
+	for setCode, paths := range brokenBySet {
+		if ctx.Err() != nil {
+			break
+		}
+		targetSet := findSet(sets, setCode)
+		cards, err := d.fetchLocalizedCards(ctx, targetSet.SearchURI)
+		if err != nil {
+			d.logf("Falha ao buscar cartas de %s: %v", strings.ToUpper(setCode), err)
+			failed = append(failed, setCode)
+			continue
+		}
+
+		// Os caminhos são recalculados como no download original, inclusive a desambiguação
+		planner := d.newPathPlanner()
+		setTasks := d.planCards(planner, cards)
+		if d.includeRelated {
+			setTasks = append(setTasks, d.planRelated(planner, targetSet, d.relatedCards(ctx, sets, targetSet, cards))...)
+		}
+		for _, task := range setTasks {
+			path := d.imagePath(task)
+			if paths[path] {
+				os.Remove(path)
+				delete(paths, path)
+				tasks = append(tasks, task)
+			}
+		}
+		for path := range paths {
+			skipped = append(skipped, path)
+		}
+		completed = append(completed, setCode)
+	}
+
+	job := d.createJob("repair", nil)
+	defer d.closeJob(job)
+	tasks = job.plan(tasks)
+	successCount := d.runTasks(ctx, tasks, job)
+
+	return downloadCompleteMsg{
+		success:   successCount == int64(len(tasks)),
+		message:   fmt.Sprintf("Reparo concluído: %d/%d imagens baixadas novamente", successCount, len(tasks)),
+		completed: completed,
+		failed:    failed,
+		skipped:   skipped,
+		cancelled: ctx.Err() != nil,
+	}
+}
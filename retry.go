@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// retryPolicy define quantas vezes e com que espera uma requisição é repetida após falhas transitórias
+type retryPolicy struct {
+	MaxRetries int           // tentativas além da primeira
+	BaseDelay  time.Duration // espera antes da primeira repetição, dobrada a cada nova tentativa
+	MaxDelay   time.Duration // teto da espera, inclusive para Retry-After
+}
+
+func newRetryPolicy(cfg Config) retryPolicy {
+	return retryPolicy{
+		MaxRetries: cfg.MaxRetries,
+		BaseDelay:  time.Duration(cfg.RetryDelayMs) * time.Millisecond,
+		MaxDelay:   2 * time.Minute,
+	}
+}
+
+// backoff calcula a espera antes da tentativa seguinte (attempt começa em 1), com jitter entre 50% e 100%
+func (p retryPolicy) backoff(attempt int) time.Duration {
+	delay := p.BaseDelay << uint(attempt-1)
+	if delay <= 0 || delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	half := int64(delay / 2)
+	return time.Duration(half + rand.Int63n(half+1))
+}
+
+// retryAfter lê o cabeçalho Retry-After, em segundos ou como data HTTP
+func retryAfter(resp *http.Response) (time.Duration, bool) {
+	value := resp.Header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return time.Until(date), true
+	}
+	return 0, false
+}
+
+// retryableStatus indica respostas que costumam se resolver sozinhas
+func retryableStatus(code int) bool {
+	return code == http.StatusTooManyRequests || code >= 500
+}
+
+// get faz um GET respeitando a pausa e o limiter e repetindo erros de rede, 429 e 5xx conforme a
+// política de retry. O cancelamento de ctx interrompe a requisição e as esperas.
+func (d *Downloader) get(ctx context.Context, limiter *rateLimiter, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	return d.do(ctx, limiter, req)
+}
+
+// do envia uma requisição já montada com as mesmas regras de get. No modo offline nenhuma
+// requisição sai.
+func (d *Downloader) do(ctx context.Context, limiter *rateLimiter, req *http.Request) (*http.Response, error) {
+	if d.offline {
+		return nil, errOffline
+	}
+	url := req.URL.String()
+	for attempt := 1; ; attempt++ {
+		if err := d.pause.wait(ctx); err != nil {
+			return nil, err
+		}
+		if err := limiter.wait(ctx); err != nil {
+			return nil, err
+		}
+		resp, err := d.client.Do(req)
+		if err == nil && !retryableStatus(resp.StatusCode) {
+			return resp, nil
+		}
+		if err != nil && ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if attempt > d.retry.MaxRetries {
+			return resp, err
+		}
+
+		wait := d.retry.backoff(attempt)
+		reason := ""
+		if err != nil {
+			reason = err.Error()
+		} else {
+			reason = fmt.Sprintf("HTTP %d", resp.StatusCode)
+			if after, ok := retryAfter(resp); ok {
+				wait = after
+				if wait > d.retry.MaxDelay {
+					wait = d.retry.MaxDelay
+				}
+			}
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+
+		atomic.AddInt64(&d.retryCount, 1)
+		d.logf("Tentativa %d/%d falhou (%s) para %s, repetindo em %s", attempt, d.retry.MaxRetries+1, reason, url, wait.Round(time.Millisecond))
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+)
+
+// Busca livre: qualquer consulta na sintaxe do Scryfall vira um download, ex:
+// "t:dragon r:mythic year>=2021", "art:squirrel" ou "is:fullart unique:prints".
+// As imagens vão para a pasta do set de cada carta, como num download por set.
+
+func (d *Downloader) searchURL(query string) string {
+	return d.apiURL("/cards/search?q=" + url.QueryEscape(query))
+}
+
+// countSearch consulta só a primeira página para saber quantas cartas a busca encontra
+func (d *Downloader) countSearch(ctx context.Context, query string) (int, error) {
+	page, err := d.fetchSearchPage(ctx, d.searchURL(query))
+	if err != nil || page == nil {
+		return 0, err
+	}
+	return page.TotalCards, nil
+}
+
+// downloadSearch baixa todas as cartas encontradas pela busca
+func (d *Downloader) downloadSearch(ctx context.Context, query string) downloadCompleteMsg {
+	cards, err := d.fetchSetCards(ctx, d.searchURL(query))
+	if ctx.Err() != nil {
+		return downloadCompleteMsg{message: "Busca interrompida", cancelled: true}
+	}
+	if err != nil {
+		return downloadCompleteMsg{message: fmt.Sprintf("❌ %v", err), failed: []string{}}
+	}
+	if len(cards) == 0 {
+		return downloadCompleteMsg{message: fmt.Sprintf("Nenhuma carta encontrada para '%s'", query), failed: []string{}}
+	}
+	d.logf("Busca '%s': %d cartas", query, len(cards))
+
+	// Sets das cartas encontradas, na ordem em que aparecem
+	var sets []string
+	for _, card := range cards {
+		if !containsString(sets, card.Set) {
+			sets = append(sets, card.Set)
+		}
+	}
+
+	planner := d.newPathPlanner()
+	allTasks := d.planCards(planner, cards)
+
+	job := d.createJob("busca "+query, nil)
+	defer d.closeJob(job)
+	allTasks = job.plan(allTasks)
+
+	successCount := d.runTasks(ctx, allTasks, job)
+
+	return downloadCompleteMsg{
+		success:           successCount > 0,
+		message:           fmt.Sprintf("✅ %d/%d imagens baixadas para a busca '%s'", successCount, len(allTasks), query),
+		completed:         sets,
+		failed:            []string{},
+		cancelled:         ctx.Err() != nil,
+		variantsKept:      planner.kept,
+		variantsCollapsed: planner.collapsed,
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// setFilter restringe os sets pegos por ALL. A expressão tem termos separados por espaço:
+//
+//	type:expansion,core   apenas esses tipos de set
+//	-type:promo,token     exclui esses tipos
+//	from:2015 to:2020-06  data de lançamento (ano, ano-mês ou data completa)
+//	min:100               mínimo de cartas no set
+//	digital               inclui sets só digitais (excluídos por padrão)
+type setFilter struct {
+	include, exclude []string
+	from, to         string
+	minCards         int
+	digital          bool
+}
+
+var filterDatePattern = regexp.MustCompile(`^\d{4}(-\d{2}(-\d{2})?)?$`)
+
+func parseSetFilter(expr string) (setFilter, error) {
+	var f setFilter
+	for _, term := range strings.Fields(strings.ToLower(expr)) {
+		key, value, hasValue := strings.Cut(term, ":")
+		if !hasValue {
+			if term != "digital" {
+				return f, fmt.Errorf("termo de filtro desconhecido %q", term)
+			}
+			f.digital = true
+			continue
+		}
+		if value == "" {
+			return f, fmt.Errorf("termo de filtro sem valor %q", term)
+		}
+
+		switch key {
+		case "type":
+			f.include = append(f.include, strings.Split(value, ",")...)
+		case "-type":
+			f.exclude = append(f.exclude, strings.Split(value, ",")...)
+		case "from", "to":
+			if !filterDatePattern.MatchString(value) {
+				return f, fmt.Errorf("data inválida %q (use AAAA, AAAA-MM ou AAAA-MM-DD)", value)
+			}
+			if key == "from" {
+				f.from = value
+			} else {
+				f.to = value
+			}
+		case "min":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 0 {
+				return f, fmt.Errorf("mínimo de cartas inválido %q", value)
+			}
+			f.minCards = n
+		default:
+			return f, fmt.Errorf("termo de filtro desconhecido %q (use type:, -type:, from:, to:, min: ou digital)", term)
+		}
+	}
+	return f, nil
+}
+
+func (f setFilter) match(set Set) bool {
+	if set.Digital && !f.digital {
+		return false
+	}
+	if len(f.include) > 0 && !containsString(f.include, set.SetType) {
+		return false
+	}
+	if containsString(f.exclude, set.SetType) {
+		return false
+	}
+	// Datas parciais comparam só o prefixo: to:2020 inclui todo o ano de 2020
+	if f.from != "" && set.ReleasedAt < f.from {
+		return false
+	}
+	if f.to != "" && set.ReleasedAt[:min(len(f.to), len(set.ReleasedAt))] > f.to {
+		return false
+	}
+	return set.CardCount >= f.minCards
+}
+
+// apply retorna os sets que passam no filtro e o total de cartas deles
+func (f setFilter) apply(sets []Set) ([]Set, int) {
+	var matched []Set
+	cards := 0
+	for _, set := range sets {
+		if f.match(set) {
+			matched = append(matched, set)
+			cards += set.CardCount
+		}
+	}
+	return matched, cards
+}
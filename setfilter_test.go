@@ -0,0 +1,76 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+var filterTestSets = []Set{
+	{Code: "ktk", SetType: "expansion", ReleasedAt: "2014-09-26", CardCount: 269},
+	{Code: "dom", SetType: "expansion", ReleasedAt: "2018-04-27", CardCount: 269},
+	{Code: "tdom", SetType: "token", ReleasedAt: "2018-04-27", CardCount: 14},
+	{Code: "ha1", SetType: "alchemy", ReleasedAt: "2019-11-14", CardCount: 20, Digital: true},
+	{Code: "iko", SetType: "expansion", ReleasedAt: "2020-04-24", CardCount: 274},
+	{Code: "p20", SetType: "promo", ReleasedAt: "2020-06-15", CardCount: 10},
+	{Code: "m21", SetType: "core", ReleasedAt: "2020-07-03", CardCount: 397},
+}
+
+func TestSetFilterMatch(t *testing.T) {
+	tests := []struct {
+		expr string
+		want []string
+	}{
+		{"", []string{"ktk", "dom", "tdom", "iko", "p20", "m21"}},
+		{"from:2015", []string{"dom", "tdom", "iko", "p20", "m21"}},
+		// Datas parciais comparam o prefixo: to:2020-06 inclui junho inteiro
+		{"to:2020-06", []string{"ktk", "dom", "tdom", "iko", "p20"}},
+		{"to:2020", []string{"ktk", "dom", "tdom", "iko", "p20", "m21"}},
+		{"to:2019", []string{"ktk", "dom", "tdom"}},
+		{"from:2020-04-24 to:2020-06-15", []string{"iko", "p20"}},
+		{"type:expansion,core", []string{"ktk", "dom", "iko", "m21"}},
+		{"TYPE:Core", []string{"m21"}},
+		{"-type:promo,token", []string{"ktk", "dom", "iko", "m21"}},
+		{"type:expansion -type:expansion", nil},
+		{"min:270", []string{"iko", "m21"}},
+		{"min:0", []string{"ktk", "dom", "tdom", "iko", "p20", "m21"}},
+		{"digital", []string{"ktk", "dom", "tdom", "ha1", "iko", "p20", "m21"}},
+		{"digital type:alchemy", []string{"ha1"}},
+		{"type:alchemy", nil},
+	}
+	for _, tt := range tests {
+		f, err := parseSetFilter(tt.expr)
+		if err != nil {
+			t.Errorf("parseSetFilter(%q): %v", tt.expr, err)
+			continue
+		}
+		matched, _ := f.apply(filterTestSets)
+		if got := setCodes(matched); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("filtro %q = %v, esperado %v", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestSetFilterApplyCards(t *testing.T) {
+	f, _ := parseSetFilter("type:core,promo")
+	if _, cards := f.apply(filterTestSets); cards != 407 {
+		t.Errorf("total de cartas = %d, esperado 407", cards)
+	}
+}
+
+func TestParseSetFilterErrors(t *testing.T) {
+	for _, expr := range []string{
+		"foo",
+		"type:",
+		"from:2020-6",
+		"to:20",
+		"from:2020/06/01",
+		"min:-1",
+		"min:abc",
+		"size:3",
+		"type:core digitall",
+	} {
+		if _, err := parseSetFilter(expr); err == nil {
+			t.Errorf("parseSetFilter(%q) deveria falhar", expr)
+		}
+	}
+}
@@ -0,0 +1,334 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// O update baixa só o que mudou desde a última sincronização: sets lançados depois dela e sets
+// já baixados cujo número de cartas mudou (spoilers, cartas adicionadas depois do lançamento).
+// Também conta como novo um set que não existia na lista anterior, mesmo com data antiga (o
+// Scryfall às vezes cadastra produtos depois do lançamento). O estado fica em
+// <downloadDir>/.sync.json; sem ele, os sets já presentes na pasta servem de ponto de partida.
+
+const syncStateFileName = ".sync.json"
+
+type syncState struct {
+	LastSync time.Time            `json:"last_sync"`
+	Sets     map[string]syncedSet `json:"sets"`      // sets baixados
+	Seen     []string             `json:"seen_sets"` // todos os sets que a API listava na última sincronização
+}
+
+// syncedSet é o que se sabia de um set na última vez em que ele foi baixado
+type syncedSet struct {
+	CardCount  int       `json:"card_count"` // -1 quando o set foi encontrado na pasta, sem sincronização
+	ReleasedAt string    `json:"released_at"`
+	SyncedAt   time.Time `json:"synced_at"`
+	Missing    int       `json:"missing,omitempty"` // imagens abandonadas depois de falhar em todas as tentativas
+}
+
+func syncStatePath(downloadDir string) string {
+	return filepath.Join(downloadDir, syncStateFileName)
+}
+
+// loadSyncState lê o estado da última sincronização. Retorna nil sem erro se ainda não houve nenhuma.
+func loadSyncState(downloadDir string) (*syncState, error) {
+	data, err := os.ReadFile(syncStatePath(downloadDir))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("erro ao ler estado da sincronização: %w", err)
+	}
+	var state syncState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("erro ao decodificar %s: %w", syncStatePath(downloadDir), err)
+	}
+	if state.Sets == nil {
+		state.Sets = make(map[string]syncedSet)
+	}
+	return &state, nil
+}
+
+func (s *syncState) save(downloadDir string) error {
+	if err := os.MkdirAll(downloadDir, 0755); err != nil {
+		return fmt.Errorf("erro ao criar diretório %s: %w", downloadDir, err)
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := syncStatePath(downloadDir)
+	if err := os.WriteFile(path+".tmp", append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("erro ao gravar estado da sincronização: %w", err)
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+// revalidateCache faz o cache da API confirmar cada resposta com o Scryfall (uma requisição
+// condicional barata quando nada mudou) até a função devolvida ser chamada. Sem isso, um set
+// lançado ou alterado dentro do TTL passaria despercebido. No modo offline vale o que estiver
+// guardado.
+func (d *Downloader) revalidateCache() (restore func()) {
+	if d.cache == nil || d.offline {
+		return func() {}
+	}
+	ttl := d.cache.ttl
+	d.cache.ttl = 0
+	return func() { d.cache.ttl = ttl }
+}
+
+// existingSetCodes encontra os sets que já têm imagens na pasta de download
+func (d *Downloader) existingSetCodes(sets []Set) (map[string]bool, error) {
+	found := make(map[string]bool)
+	err := filepath.Walk(d.downloadDir, func(path string, info os.FileInfo, err error) error {
+		if os.IsNotExist(err) && path == d.downloadDir {
+			return filepath.SkipDir
+		}
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if path != d.downloadDir && strings.HasPrefix(info.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".jpg", ".jpeg", ".png":
+			if code := d.setFromPath(sets, path); code != "" {
+				found[code] = true
+			}
+		}
+		return nil
+	})
+	return found, err
+}
+
+// updatePlan separa os sets que o update vai baixar
+type updatePlan struct {
+	state     *syncState
+	baseline  string // data (AAAA-MM-DD) a partir da qual um set conta como novo
+	newSets   []Set
+	changed   []Set
+	unchanged int
+}
+
+func (p updatePlan) codes() []string {
+	return append(setCodes(p.newSets), setCodes(p.changed)...)
+}
+
+// planUpdate compara a lista de sets com o estado salvo. since substitui a data da última
+// sincronização; full confere de novo todos os sets já baixados.
+func (d *Downloader) planUpdate(sets []Set, filter setFilter, since string, full bool) (updatePlan, error) {
+	state, err := loadSyncState(d.downloadDir)
+	if err != nil {
+		return updatePlan{}, err
+	}
+	plan := updatePlan{state: state}
+	if state == nil {
+		// Primeira sincronização: parte dos sets que já estão na pasta
+		existing, err := d.existingSetCodes(sets)
+		if err != nil {
+			return plan, fmt.Errorf("erro ao ler a pasta de download: %w", err)
+		}
+		plan.state = &syncState{Sets: make(map[string]syncedSet)}
+		for _, set := range sets {
+			if existing[set.Code] {
+				plan.state.Sets[set.Code] = syncedSet{CardCount: -1, ReleasedAt: set.ReleasedAt}
+				if set.ReleasedAt > plan.baseline {
+					plan.baseline = set.ReleasedAt
+				}
+			}
+		}
+	} else {
+		plan.baseline = state.LastSync.Format("2006-01-02")
+	}
+	if since != "" {
+		plan.baseline = since
+	}
+	if plan.baseline == "" {
+		return plan, fmt.Errorf("nenhum set baixado em %s para comparar; baixe algum set antes ou informe -since AAAA-MM-DD", d.downloadDir)
+	}
+
+	seen := make(map[string]bool)
+	for _, code := range plan.state.Seen {
+		seen[code] = true
+	}
+	for _, set := range sets {
+		synced, tracked := plan.state.Sets[set.Code]
+		isNew := set.ReleasedAt >= plan.baseline || (len(seen) > 0 && !seen[set.Code])
+		switch {
+		case tracked && (full || synced.CardCount != set.CardCount):
+			plan.changed = append(plan.changed, set)
+		case tracked:
+			plan.unchanged++
+		case isNew && set.CardCount > 0 && filter.match(set):
+			plan.newSets = append(plan.newSets, set)
+		}
+	}
+	// Do mais antigo para o mais novo, como foram lançados
+	sort.SliceStable(plan.newSets, func(i, j int) bool { return plan.newSets[i].ReleasedAt < plan.newSets[j].ReleasedAt })
+	return plan, nil
+}
+
+// updateResult traz o resultado do download e o que entra no changelog
+type updateResult struct {
+	downloadCompleteMsg
+	newSets []Set
+	changed []Set
+	before  map[string]int // card_count de cada set alterado antes do update (-1 se desconhecido)
+	added   map[string]int // imagens que não existiam antes, por set
+}
+
+// updateJob reaproveita o job inacabado de um update anterior, se houver, em vez de abrir outro
+// journal a cada execução. Os sets do plano são paginados de novo dentro dele, e as tasks que já
+// estavam no journal mantêm o histórico de falhas (veja jobJournal.plan).
+func (d *Downloader) updateJob(codes []string) *jobJournal {
+	jobs, err := listJobs(d.downloadDir)
+	if err != nil {
+		d.logf("Erro ao listar jobs: %v", err)
+	}
+	for _, job := range jobs {
+		if !strings.HasPrefix(job.Description, "update ") {
+			continue
+		}
+		if err := job.openForAppend(); err != nil {
+			d.logf("%v", err)
+			break
+		}
+		d.logf("Continuando job %s", job.ID)
+		return job
+	}
+	return d.createJob("update "+strings.Join(codes, ","), codes)
+}
+
+// runUpdate baixa os sets do plano e grava o estado. Um set só é marcado como sincronizado quando
+// todas as suas imagens existem ao final ou foram abandonadas depois de maxTaskFailures falhas;
+// do contrário o próximo update tenta de novo, no mesmo journal.
+func (d *Downloader) runUpdate(ctx context.Context, sets []Set, plan updatePlan) updateResult {
+	result := updateResult{newSets: plan.newSets, changed: plan.changed, before: make(map[string]int), added: make(map[string]int)}
+	for _, set := range plan.changed {
+		result.before[set.Code] = plan.state.Sets[set.Code].CardCount
+	}
+
+	var tasks []imageTask
+	var planner *pathPlanner
+	if codes := plan.codes(); len(codes) > 0 {
+		job := d.updateJob(codes)
+		var leftover []imageTask
+		if job != nil {
+			for _, code := range job.unplannedSets() {
+				if !containsString(codes, code) {
+					codes = append(codes, code) // Pedido por um update anterior e não paginado
+				}
+			}
+			leftover = job.pendingTasks()
+		}
+		planner = d.newPathPlanner()
+		var completed, failed []string
+		tasks, completed, failed = d.planSets(ctx, job, planner, sets, codes)
+
+		// Pendências de uma execução anterior que não foram planejadas de novo agora
+		planned := make(map[int]bool)
+		for _, task := range tasks {
+			planned[task.ID] = true
+		}
+		for _, task := range leftover {
+			if !planned[task.ID] {
+				tasks = append(tasks, task)
+			}
+		}
+
+		existed := make(map[string]bool)
+		var run []imageTask
+		for _, task := range tasks {
+			if _, err := os.Stat(d.imagePath(task)); err == nil {
+				existed[d.imagePath(task)] = true
+			}
+			if !job.abandoned(task.ID) {
+				run = append(run, task)
+			}
+		}
+		successCount := d.runTasks(ctx, run, job)
+
+		counted := make(map[string]bool) // Verso padrão e outras imagens compartilhadas contam uma vez
+		incomplete := make(map[string]bool)
+		missing := make(map[string]int)
+		for _, task := range tasks {
+			path := d.imagePath(task)
+			if _, err := os.Stat(path); err != nil {
+				if job.abandoned(task.ID) {
+					missing[strings.ToLower(task.SetCode)]++
+				} else {
+					incomplete[strings.ToLower(task.SetCode)] = true
+				}
+			} else if !existed[path] && !counted[path] {
+				result.added[strings.ToLower(task.SetCode)]++
+				counted[path] = true
+			}
+		}
+		d.closeJob(job)
+
+		now := time.Now()
+		for _, code := range completed {
+			if set := findSet(sets, code); set != nil && !incomplete[set.Code] {
+				plan.state.Sets[set.Code] = syncedSet{CardCount: set.CardCount, ReleasedAt: set.ReleasedAt, SyncedAt: now, Missing: missing[set.Code]}
+				if missing[set.Code] > 0 {
+					d.logf("Set %s sincronizado sem %d imagens que falharam %d vezes", strings.ToUpper(set.Code), missing[set.Code], maxTaskFailures)
+				}
+			}
+		}
+		result.downloadCompleteMsg = downloadCompleteMsg{
+			success:           len(failed) == 0 && len(incomplete) == 0,
+			message:           fmt.Sprintf("Update finalizado: %d sets verificados, %d imagens processadas", len(completed), successCount),
+			completed:         completed,
+			failed:            failed,
+			cancelled:         ctx.Err() != nil,
+			variantsKept:      planner.kept,
+			variantsCollapsed: planner.collapsed,
+		}
+	} else {
+		result.downloadCompleteMsg = downloadCompleteMsg{success: true, message: "Tudo em dia: nenhum set novo ou alterado"}
+	}
+
+	// A data e a lista de sets só avançam quando nada ficou para trás
+	if result.success && !result.cancelled {
+		plan.state.LastSync = time.Now()
+		plan.state.Seen = setCodes(sets)
+	} else if plan.state.LastSync.IsZero() {
+		// Primeira sincronização incompleta: guarda o ponto de partida, senão o próximo update
+		// tomaria como novo todo set já lançado
+		plan.state.LastSync, _ = time.Parse("2006-01-02", plan.baseline)
+	}
+	if err := plan.state.save(d.downloadDir); err != nil {
+		d.logf("%v", err)
+	}
+	return result
+}
+
+// changelog descreve o que o update acrescentou, uma linha por set
+func (r updateResult) changelog() []string {
+	var lines []string
+	for _, set := range r.newSets {
+		lines = append(lines, fmt.Sprintf("+ %s %s (%s): %d cartas, %d imagens", strings.ToUpper(set.Code), set.Name, set.ReleasedAt, set.CardCount, r.added[set.Code]))
+	}
+	for _, set := range r.changed {
+		before := r.before[set.Code]
+		if r.added[set.Code] == 0 && (before == set.CardCount || before < 0) {
+			continue // Conferido sem novidades
+		}
+		line := fmt.Sprintf("~ %s %s: +%d imagens", strings.ToUpper(set.Code), set.Name, r.added[set.Code])
+		if before >= 0 && before != set.CardCount {
+			line += fmt.Sprintf(" (%d → %d cartas)", before, set.CardCount)
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"io"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// O watch fica rodando (num servidor de casa, por exemplo) e a cada intervalo faz o mesmo que o
+// update: revalida a lista de sets e baixa os sets lançados e as cartas novas de sets que
+// ganharam cartas (temporada de spoilers). Cada ciclo fica registrado num arquivo de log.
+
+// watchLogPath é o arquivo de log do watch, por padrão dentro da pasta de download
+func watchLogPath(cfg Config) string {
+	if cfg.WatchLog != "" {
+		return cfg.WatchLog
+	}
+	return filepath.Join(cfg.DownloadDir, ".watch.log")
+}
+
+// timestampWriter prefixa cada linha com data e hora, para o log do watch
+type timestampWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (t *timestampWriter) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	prefix := time.Now().Format("2006-01-02 15:04:05 ")
+	lines := strings.SplitAfter(string(p), "\n")
+	var b strings.Builder
+	for _, line := range lines {
+		if line != "" {
+			b.WriteString(prefix + line)
+		}
+	}
+	if _, err := io.WriteString(t.w, b.String()); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// watchCycle faz uma verificação completa e registra o changelog no log
+func (d *Downloader) watchCycle(ctx context.Context, filter setFilter, since string) (updateResult, error) {
+	sets, err := d.fetchSets(ctx)
+	if err != nil {
+		return updateResult{}, err
+	}
+	plan, err := d.planUpdate(sets, filter, since, false)
+	if err != nil {
+		return updateResult{}, err
+	}
+	d.logf("Sets novos: %d | sets alterados: %d | sem mudanças: %d", len(plan.newSets), len(plan.changed), plan.unchanged)
+
+	result := d.runUpdate(ctx, sets, plan)
+	for _, line := range result.changelog() {
+		d.logf("%s", line)
+	}
+	d.logf("%s", result.message)
+	if len(result.failed) > 0 {
+		d.logf("Com falha (%d): %s", len(result.failed), strings.ToUpper(strings.Join(result.failed, ", ")))
+	}
+	return result, nil
+}
+
+// watch repete watchCycle a cada interval até ctx ser cancelado; since, como no update, vale só
+// para o primeiro ciclo, que já grava o estado da sincronização. Um erro no primeiro ciclo
+// (configuração, pasta sem sets) encerra o watch; nos seguintes ele é registrado e o ciclo é
+// tentado de novo no próximo intervalo, já que a causa costuma ser a rede ou a API.
+func (d *Downloader) watch(ctx context.Context, filter setFilter, since string, interval time.Duration) error {
+	restore := d.revalidateCache()
+	defer restore()
+
+	for cycle := 1; ; cycle++ {
+		d.logf("Ciclo %d: verificando lançamentos", cycle)
+		if _, err := d.watchCycle(ctx, filter, since); err != nil && ctx.Err() == nil {
+			if cycle == 1 {
+				return err
+			}
+			d.logf("Ciclo %d falhou: %v", cycle, err)
+		} else {
+			since = ""
+		}
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		next := time.Now().Add(interval)
+		d.logf("Próxima verificação às %s", next.Format("2006-01-02 15:04"))
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(interval):
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+)
+
+// Um set novo com uma carta cuja imagem nunca baixa: cada ciclo do watch tenta de novo no mesmo
+// journal, até a imagem ser abandonada e o set entrar no estado da sincronização
+func TestWatchCycleReusesJob(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/sets":
+			fmt.Fprintf(w, `{"data":[{"code":"new","name":"New Set","search_uri":"%s/cards/search?q=e:new","set_type":"expansion","card_count":1,"released_at":"2024-01-05"}]}`, server.URL)
+		case "/cards/search":
+			fmt.Fprintf(w, `{"data":[{"name":"Dead Link","set":"new","collector_number":"1","layout":"normal","lang":"en","image_uris":{"large":"%s/img/dead.jpg"}}]}`, server.URL)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	cfg := defaultConfig()
+	cfg.DownloadDir = t.TempDir()
+	cfg.APIBase = server.URL
+	cfg.Cache = false
+	d := NewDownloader(cfg)
+	d.logOutput = io.Discard
+
+	journals := func() []string {
+		paths, err := filepath.Glob(filepath.Join(jobsDir(cfg.DownloadDir), "*.jsonl"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return paths
+	}
+
+	since := "2024-01-01"
+	for cycle := 1; cycle < maxTaskFailures; cycle++ {
+		result, err := d.watchCycle(context.Background(), setFilter{}, since)
+		if err != nil {
+			t.Fatalf("ciclo %d: %v", cycle, err)
+		}
+		if result.success {
+			t.Errorf("ciclo %d terminou com sucesso, esperado falha da imagem", cycle)
+		}
+		if got := journals(); len(got) != 1 {
+			t.Fatalf("ciclo %d: %d journals, esperado 1: %v", cycle, len(got), got)
+		}
+		since = ""
+	}
+
+	// Na última tentativa a imagem é abandonada: o journal some e o set conta como sincronizado
+	if _, err := d.watchCycle(context.Background(), setFilter{}, ""); err != nil {
+		t.Fatal(err)
+	}
+	if got := journals(); len(got) != 0 {
+		t.Errorf("journals restantes: %v", got)
+	}
+	state, err := loadSyncState(cfg.DownloadDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if synced, ok := state.Sets["new"]; !ok || synced.Missing != 1 {
+		t.Errorf("estado do set new = %+v, %v; esperado sincronizado com 1 imagem faltando", synced, ok)
+	}
+	if state.LastSync.IsZero() {
+		t.Error("LastSync não avançou")
+	}
+}